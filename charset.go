@@ -0,0 +1,267 @@
+package csvbuddy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrCharset signals that a character cannot be encoded in the selected Charset.
+var ErrCharset = errors.New("character cannot be encoded")
+
+// Charset is a character set that CSV input and output streams are transcoded from and to.
+// Records are always UTF-8 encoded.
+type Charset int
+
+const (
+	// UTF8 does not transcode. This is the default.
+	UTF8 Charset = iota
+
+	// UTF16 decodes UTF-16 in the byte order indicated by the byte order mark,
+	// or little endian if there is none. It encodes little endian UTF-16
+	// preceded by a byte order mark.
+	UTF16
+
+	// UTF16LE is little endian UTF-16 without byte order mark.
+	// A byte order mark is skipped when decoding.
+	UTF16LE
+
+	// UTF16BE is big endian UTF-16 without byte order mark.
+	// A byte order mark is skipped when decoding.
+	UTF16BE
+
+	// ISO88591 is ISO-8859-1 (Latin-1).
+	ISO88591
+
+	// Windows1252 is the Windows-1252 code page, a superset of the printable characters of ISO-8859-1.
+	Windows1252
+)
+
+func (c Charset) String() string {
+	switch c {
+	case UTF8:
+		return "UTF-8"
+	case UTF16:
+		return "UTF-16"
+	case UTF16LE:
+		return "UTF-16LE"
+	case UTF16BE:
+		return "UTF-16BE"
+	case ISO88591:
+		return "ISO-8859-1"
+	case Windows1252:
+		return "Windows-1252"
+	}
+	return fmt.Sprintf("Charset(%d)", int(c))
+}
+
+// isUTF16 reports whether c is one of the UTF-16 charsets.
+func (c Charset) isUTF16() bool {
+	return c == UTF16 || c == UTF16LE || c == UTF16BE
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F to runes.
+// Undefined bytes map to the C1 control characters like in ISO-8859-1.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// NewDecoder returns a reader that transcodes r from c to UTF-8.
+func (c Charset) NewDecoder(r io.Reader) io.Reader {
+	if c == UTF8 {
+		return r
+	}
+	return &charsetReader{r: r, charset: c, bigEndian: c == UTF16BE}
+}
+
+// NewEncoder returns a writer that transcodes UTF-8 to c and writes it to w.
+func (c Charset) NewEncoder(w io.Writer) io.Writer {
+	if c == UTF8 {
+		return w
+	}
+	return &charsetWriter{w: w, charset: c}
+}
+
+// charsetReader transcodes an input stream to UTF-8.
+type charsetReader struct {
+	r         io.Reader
+	charset   Charset
+	bigEndian bool
+	started   bool   // the byte order mark has been checked
+	raw       []byte // bytes read but not yet transcoded
+	out       []byte // transcoded bytes not yet returned
+	err       error
+}
+
+func (c *charsetReader) Read(p []byte) (int, error) {
+	for len(c.out) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		var buf [4096]byte
+		n, err := c.r.Read(buf[:])
+		c.raw = append(c.raw, buf[:n]...)
+		c.err = err
+		c.transcode(err != nil)
+	}
+
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// transcode converts the raw bytes to UTF-8. Incomplete characters
+// are kept for the next call unless the end of the input has been reached.
+func (c *charsetReader) transcode(eof bool) {
+	c.out = c.out[:0]
+	raw := c.raw
+
+	if !c.charset.isUTF16() {
+		for _, b := range raw {
+			r := rune(b)
+			if c.charset == Windows1252 && b >= 0x80 && b < 0xA0 {
+				r = windows1252[b-0x80]
+			}
+			c.out = utf8.AppendRune(c.out, r)
+		}
+		c.raw = c.raw[:0]
+		return
+	}
+
+	if !c.started {
+		if len(raw) < 2 && !eof {
+			return
+		}
+		c.started = true
+		if len(raw) >= 2 && raw[0] == 0xFF && raw[1] == 0xFE {
+			c.bigEndian, raw = false, raw[2:]
+		} else if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+			c.bigEndian, raw = true, raw[2:]
+		}
+	}
+
+	unit := func(b []byte) rune {
+		if c.bigEndian {
+			return rune(b[0])<<8 | rune(b[1])
+		}
+		return rune(b[1])<<8 | rune(b[0])
+	}
+
+	for len(raw) >= 2 {
+		r := unit(raw)
+		if utf16.IsSurrogate(r) {
+			if len(raw) < 4 && !eof {
+				break // wait for the low surrogate
+			} else if len(raw) >= 4 {
+				if r2 := utf16.DecodeRune(r, unit(raw[2:])); r2 != utf8.RuneError {
+					c.out = utf8.AppendRune(c.out, r2)
+					raw = raw[4:]
+					continue
+				}
+			}
+			r = utf8.RuneError
+		}
+		c.out = utf8.AppendRune(c.out, r)
+		raw = raw[2:]
+	}
+
+	if eof && len(raw) > 0 { // odd number of bytes
+		c.out = utf8.AppendRune(c.out, utf8.RuneError)
+		raw = nil
+	}
+
+	c.raw = append(c.raw[:0], raw...)
+}
+
+// charsetWriter transcodes UTF-8 to an output stream.
+type charsetWriter struct {
+	w       io.Writer
+	charset Charset
+	started bool   // the first character has been written
+	partial []byte // incomplete UTF-8 sequence of the previous write
+	out     []byte
+}
+
+func (c *charsetWriter) Write(p []byte) (int, error) {
+	in := p
+	if len(c.partial) > 0 {
+		in = append(c.partial, p...)
+		c.partial = nil
+	}
+
+	c.out = c.out[:0]
+	for len(in) > 0 {
+		if !utf8.FullRune(in) {
+			c.partial = append([]byte{}, in...)
+			break
+		}
+
+		r, size := utf8.DecodeRune(in)
+		in = in[size:]
+
+		first := !c.started
+		c.started = true
+
+		switch c.charset {
+		case UTF16, UTF16LE, UTF16BE:
+			if first && c.charset == UTF16 && r != '\uFEFF' {
+				c.out = append(c.out, 0xFF, 0xFE)
+			}
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				c.out = c.appendUnit(c.out, r1)
+				c.out = c.appendUnit(c.out, r2)
+			} else {
+				c.out = c.appendUnit(c.out, r)
+			}
+		default:
+			if first && r == '\uFEFF' {
+				continue // a byte order mark is meaningless in a single byte charset
+			} else if b, ok := c.encodeByte(r); ok {
+				c.out = append(c.out, b)
+			} else {
+				return 0, fmt.Errorf("%w: %q in %s", ErrCharset, r, c.charset)
+			}
+		}
+	}
+
+	if _, err := c.w.Write(c.out); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (c *charsetWriter) appendUnit(b []byte, r rune) []byte {
+	if c.charset == UTF16BE {
+		return append(b, byte(r>>8), byte(r))
+	}
+	return append(b, byte(r), byte(r>>8))
+}
+
+func (c *charsetWriter) encodeByte(r rune) (byte, bool) {
+	if c.charset == Windows1252 {
+		for i, x := range windows1252 {
+			if x == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r >= 0x80 && r < 0xA0 {
+			return 0, false
+		}
+	}
+	return byte(r), r < 0x100
+}
+
+// SetCharset causes the Decoder to transcode the input stream from c to UTF-8.
+// Offsets reported by the DecoderIterator refer to the transcoded text,
+// so a Checkpoint can only be used to resume UTF-8 input.
+func (d *Decoder) SetCharset(c Charset) { d.charset = c }
+
+// SetCharset causes the Encoder to transcode the output stream from UTF-8 to c.
+func (e *Encoder) SetCharset(c Charset) { e.charset = c }
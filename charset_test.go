@@ -0,0 +1,145 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBOM(t *testing.T) {
+	testdata := bom + "start,end\n1,2\n"
+
+	var data []testPeriod
+	if err := Unmarshal([]byte(testdata), &data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}}) {
+		t.Fatal("not equal", data)
+	}
+
+	// the byte order mark is skipped for custom Readers and counted in the offset
+	readerFunc := func(r io.Reader) Reader { return csv.NewReader(r) }
+
+	var row testPeriod
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SetReaderFunc(readerFunc)
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	} else if !iter.Scan() || row != (testPeriod{1, 2}) {
+		t.Fatal(iter.Err(), row)
+	} else if iter.InputOffset() != int64(len(testdata)) {
+		t.Fatal("wrong offset", iter.InputOffset())
+	}
+
+	f := strings.NewReader(testdata)
+	d = NewDecoder(nil)
+	d.SetReaderFunc(readerFunc)
+	if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}}) {
+		t.Fatal("not equal", data)
+	}
+}
+
+func TestCharsetDecoder(t *testing.T) {
+	for _, test := range []struct {
+		Charset Charset
+		Input   []byte
+		Text    string
+	}{
+		{UTF16, []byte{0xFF, 0xFE, 'a', 0, 0xAC, 0x20}, "a€"},
+		{UTF16, []byte{0xFE, 0xFF, 0, 'a', 0x20, 0xAC}, "a€"},
+		{UTF16, []byte{'a', 0, 0x3D, 0xD8, 0x00, 0xDE}, "a😀"},
+		{UTF16LE, []byte{0xFF, 0xFE, 'a', 0, 'b'}, "a�"},
+		{UTF16BE, []byte{0, 'a', 0xD8, 0x3D}, "a�"},
+		{ISO88591, []byte{'a', 0xE9, 0x80}, "aé\u0080"},
+		{Windows1252, []byte{'a', 0xE9, 0x80, 0x81}, "aé€\u0081"},
+	} {
+		// read one byte at a time to split the characters
+		r := test.Charset.NewDecoder(&oneByteReader{test.Input})
+		if b, err := io.ReadAll(r); err != nil {
+			t.Fatal(err)
+		} else if string(b) != test.Text {
+			t.Fatalf("%s: %q", test.Charset, b)
+		}
+	}
+}
+
+type oneByteReader struct {
+	b []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	} else if len(p) == 0 {
+		return 0, nil
+	}
+	p[0], r.b = r.b[0], r.b[1:]
+	return 1, nil
+}
+
+func TestCharsetEncoder(t *testing.T) {
+	for _, test := range []struct {
+		Charset Charset
+		Text    string
+		Output  []byte
+	}{
+		{UTF16, "a😀", []byte{0xFF, 0xFE, 'a', 0, 0x3D, 0xD8, 0x00, 0xDE}},
+		{UTF16, bom + "a", []byte{0xFF, 0xFE, 'a', 0}},
+		{UTF16BE, "a€", []byte{0, 'a', 0x20, 0xAC}},
+		{ISO88591, bom + "aé", []byte{'a', 0xE9}},
+		{Windows1252, "a€é", []byte{'a', 0x80, 0xE9}},
+	} {
+		var b bytes.Buffer
+		w := test.Charset.NewEncoder(&b)
+
+		// write one byte at a time to split the characters
+		for i := 0; i < len(test.Text); i++ {
+			if _, err := w.Write([]byte{test.Text[i]}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if !bytes.Equal(b.Bytes(), test.Output) {
+			t.Fatalf("%s: %v", test.Charset, b.Bytes())
+		}
+	}
+
+	if _, err := ISO88591.NewEncoder(io.Discard).Write([]byte("€")); !errors.Is(err, ErrCharset) {
+		t.Fatal("expected ErrCharset", err)
+	}
+}
+
+func TestCharsetRoundTrip(t *testing.T) {
+	type row struct {
+		Name string `csv:"name"`
+	}
+
+	data := []row{{"Renée"}, {"Zoë"}}
+
+	for _, charset := range []Charset{UTF16, UTF16LE, UTF16BE, ISO88591, Windows1252} {
+		var b bytes.Buffer
+		e := NewEncoder(&b)
+		e.SetCharset(charset)
+		e.SetDialect(DialectExcel)
+		if err := e.Encode(&data); err != nil {
+			t.Fatal(err)
+		} else if strings.Contains(b.String(), "Zoë") {
+			t.Fatal("output is not transcoded", charset)
+		}
+
+		var data2 []row
+		d := NewDecoder(&b)
+		d.SetCharset(charset)
+		if err := d.Decode(&data2); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, data2) {
+			t.Fatal("not equal", charset, data2)
+		}
+	}
+}
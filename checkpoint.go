@@ -0,0 +1,106 @@
+package csvbuddy
+
+import (
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Checkpoint is the position of a DecoderIterator in its input stream.
+// It can be persisted and passed to Decoder.SetCheckpoint
+// to resume decoding after the last row that was scanned.
+type Checkpoint struct {
+	Offset int64    // byte offset following the last row
+	Line   int      // line following the last row, 0 if unknown
+	Header []string // header of the input stream
+}
+
+// recordEnd is the position following a record.
+type recordEnd struct {
+	line   int   // line of the record
+	next   int   // line following the record, 0 if unknown
+	offset int64 // byte offset following the record, -1 if unknown
+}
+
+// inputOffset returns the byte offset of the current position of r
+// or -1 if r does not implement InputOffset.
+func inputOffset(r interface{}) int64 {
+	if ir, ok := r.(interface{ InputOffset() int64 }); ok {
+		return ir.InputOffset()
+	}
+	return -1
+}
+
+// endOf returns the position following the record that was last read from r.
+func endOf(r interface{}, record []string) (end recordEnd) {
+	end.offset = inputOffset(r)
+	if n := len(record); n > 0 {
+		end.line, _ = fieldPos(r, 0)
+		if line, _ := fieldPos(r, n-1); line > 0 {
+			// the last field may contain quoted newlines
+			end.next = line + strings.Count(record[n-1], "\n") + 1
+		}
+	}
+	return
+}
+
+// SetCheckpoint causes the Decoder to resume decoding at cp.
+// The header is taken from the checkpoint instead of the input stream.
+// The input stream is positioned at cp.Offset by seeking
+// if it implements io.Seeker and by discarding bytes otherwise.
+func (d *Decoder) SetCheckpoint(cp Checkpoint) { d.checkpoint = &cp }
+
+// resume prepares the state to decode the records following the checkpoint.
+func (s *decodeState) resume(d *Decoder, structType reflect.Type) (err error) {
+	cp := d.checkpoint
+
+	if seeker, ok := d.reader.(io.Seeker); ok {
+		_, err = seeker.Seek(cp.Offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, d.reader, cp.Offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	header := cp.Header
+	if len(header) == 0 {
+		if header, err = d.structHeader(structType); err != nil {
+			return err
+		}
+	}
+
+	bom := &bomReader{r: d.charset.NewDecoder(s.obs.reader(d.reader))}
+	r := &lineOffsetReader{Reader: &bomOffsetReader{d.newReader(bom), bom}, inputOffset: cp.Offset}
+	if cp.Line > 0 {
+		r.offset = cp.Line - 1
+	}
+
+	if err = s.setup(d, r, structType, header); err != nil {
+		return err
+	}
+
+	s.end = recordEnd{next: cp.Line, offset: cp.Offset}
+	return nil
+}
+
+// InputOffset returns the byte offset following the last row returned by Scan
+// or -1 if the Reader does not implement InputOffset, like csv.Reader does.
+func (d *DecoderIterator) InputOffset() int64 {
+	return d.end.offset
+}
+
+// Line returns the line of the last row returned by Scan
+// or 0 if the Reader does not implement FieldPos, like csv.Reader does.
+func (d *DecoderIterator) Line() int {
+	return d.end.line
+}
+
+// Checkpoint returns the position following the last row returned by Scan.
+func (d *DecoderIterator) Checkpoint() Checkpoint {
+	return Checkpoint{
+		Offset: d.end.offset,
+		Line:   d.end.next,
+		Header: append([]string{}, d.state.header...),
+	}
+}
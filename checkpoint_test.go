@@ -0,0 +1,63 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoderIterateCheckpoint(t *testing.T) {
+	testdata := "start,end\n1,2\n\"3\",\"4\n\"\n5,6\n7,x\n"
+
+	for _, n := range []int{0, 2} {
+		var row testPeriod
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(n)
+		d.SetMapFunc(func(_, v string) string { return strings.TrimSpace(v) })
+
+		iter, err := d.Iterate(&row)
+		if err != nil {
+			t.Fatal(err)
+		} else if cp := iter.Checkpoint(); cp.Offset != 10 || cp.Line != 2 {
+			t.Fatal("wrong checkpoint after header", cp)
+		}
+
+		for i := 0; i < 2; i++ {
+			if !iter.Scan() {
+				t.Fatal(iter.Err())
+			}
+		}
+
+		cp := iter.Checkpoint()
+		if iter.Line() != 3 || iter.InputOffset() != 23 {
+			t.Fatal("wrong position", iter.Line(), iter.InputOffset())
+		} else if cp.Offset != 23 || cp.Line != 5 || strings.Join(cp.Header, ",") != "start,end" {
+			t.Fatal("wrong checkpoint", cp)
+		}
+		iter.Close()
+
+		// resume from a seeker and from a plain reader
+		for _, r := range []interface {
+			Read([]byte) (int, error)
+		}{strings.NewReader(testdata), bytes.NewBufferString(testdata)} {
+			d := NewDecoder(r)
+			d.SetCheckpoint(cp)
+
+			iter, err := d.Iterate(&row)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pe *csv.ParseError
+			if !iter.Scan() || row != (testPeriod{5, 6}) {
+				t.Fatal("expected row 5,6", row, iter.Err())
+			} else if iter.Line() != 5 || iter.InputOffset() != 27 {
+				t.Fatal("wrong position after resume", iter.Line(), iter.InputOffset())
+			} else if iter.Scan() || !errors.As(iter.Err(), &pe) || pe.Line != 6 {
+				t.Fatal("expected error on line 6", iter.Err())
+			}
+		}
+	}
+}
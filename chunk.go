@@ -0,0 +1,318 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// minChunkSize is the smallest number of bytes decoded by one goroutine.
+const minChunkSize = 64 << 10
+
+// chunk is a range of a file that starts and ends at record boundaries.
+type chunk struct {
+	start, end int64
+	line       int // line number of the first record
+}
+
+// segmentCount holds the number of quotes and newlines in a range of a file.
+type segmentCount struct {
+	quotes, newlines int
+	err              error
+}
+
+func countSegment(f io.ReaderAt, start, end int64) (c segmentCount) {
+	buf := make([]byte, 32<<10)
+	for off := start; off < end; {
+		n := int64(len(buf))
+		if end-off < n {
+			n = end - off
+		}
+		m, err := f.ReadAt(buf[:n], off)
+		c.quotes += bytes.Count(buf[:m], []byte{'"'})
+		c.newlines += bytes.Count(buf[:m], []byte{'\n'})
+		if off += int64(m); err != nil && (err != io.EOF || off < end) {
+			c.err = err
+			return
+		}
+	}
+	return
+}
+
+// nextBoundary returns the offset following the first newline at or after off
+// that is not enclosed in quotes, and the number of newlines in between.
+// It returns size if there is no such newline.
+func nextBoundary(f io.ReaderAt, size, off int64, quoted bool) (int64, int, error) {
+	var newlines int
+	buf := make([]byte, 4<<10)
+	for off < size {
+		n := int64(len(buf))
+		if size-off < n {
+			n = size - off
+		}
+		m, err := f.ReadAt(buf[:n], off)
+		for i, c := range buf[:m] {
+			if c == '"' {
+				quoted = !quoted
+			} else if c == '\n' {
+				newlines++
+				if !quoted {
+					return off + int64(i) + 1, newlines, nil
+				}
+			}
+		}
+		if off += int64(m); err != nil && (err != io.EOF || off < size) {
+			return 0, 0, err
+		}
+	}
+	return size, newlines, nil
+}
+
+// splitChunks splits the range [start, size) of f into at most n chunks.
+// The quote state is derived from the number of quotes preceding each split,
+// which is exact for files that do not contain bare quotes.
+func splitChunks(f io.ReaderAt, start, size int64, line, n int) ([]chunk, error) {
+	if max := int((size - start) / minChunkSize); n > max {
+		n = max
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	// tentative split offsets
+	offsets := make([]int64, n+1)
+	for i := range offsets {
+		offsets[i] = start + (size-start)*int64(i)/int64(n)
+	}
+
+	// count quotes and newlines of the segments concurrently
+	counts := make([]segmentCount, n)
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for i := 0; i < n-1; i++ {
+		go func(i int) {
+			defer wg.Done()
+			counts[i] = countSegment(f, offsets[i], offsets[i+1])
+		}(i)
+	}
+	wg.Wait()
+
+	chunks := []chunk{{start: start, line: line}}
+	quotes, newlines := 0, line-1
+	for i := 1; i < n; i++ {
+		if err := counts[i-1].err; err != nil {
+			return nil, err
+		}
+
+		quotes += counts[i-1].quotes
+		newlines += counts[i-1].newlines
+
+		// the previous boundary was found beyond this offset
+		if offsets[i] < chunks[len(chunks)-1].start {
+			continue
+		}
+
+		boundary, nl, err := nextBoundary(f, size, offsets[i], quotes%2 == 1)
+		if err != nil {
+			return nil, err
+		} else if boundary >= size {
+			break
+		}
+
+		chunks[len(chunks)-1].end = boundary
+		chunks = append(chunks, chunk{start: boundary, line: newlines + nl + 1})
+	}
+
+	chunks[len(chunks)-1].end = size
+	return chunks, nil
+}
+
+// lineOffsetReader adds an offset to the line numbers
+// reported by FieldPos, parse errors and limit errors,
+// and to the byte offset reported by InputOffset.
+type lineOffsetReader struct {
+	Reader
+	offset      int
+	inputOffset int64
+}
+
+func (r *lineOffsetReader) InputOffset() int64 {
+	if offset := inputOffset(r.Reader); offset >= 0 {
+		return offset + r.inputOffset
+	}
+	return -1
+}
+
+func (r *lineOffsetReader) Read() ([]string, error) {
+	record, err := r.Reader.Read()
+	var perr *csv.ParseError
+	var lerr *LimitError
+	if errors.As(err, &perr) {
+		perr.StartLine += r.offset
+		perr.Line += r.offset
+	} else if errors.As(err, &lerr) {
+		lerr.Line += r.offset
+	}
+	return record, err
+}
+
+func (r *lineOffsetReader) FieldPos(field int) (line, column int) {
+	line, column = fieldPos(r.Reader, field)
+	return line + r.offset, column
+}
+
+// DecodeFileParallel decodes a CSV file using a Decoder with default options.
+// See Decoder.DecodeFileParallel.
+func DecodeFileParallel(f io.ReaderAt, size int64, v interface{}) error {
+	return NewDecoder(nil).DecodeFileParallel(f, size, v)
+}
+
+// DecodeFileParallel decodes the first size bytes of f as a slice of structs
+// and stores it in v, which must be a pointer to a slice of structs
+// or a pointer to a slice of pointers to structs.
+// The file is split into chunks at record boundaries that are decoded concurrently
+// using the shared header. The number of goroutines is set by SetConcurrency
+// and defaults to GOMAXPROCS. Line numbers in errors are relative to the start of the file
+// and the error of the first failing row is reported.
+// Record boundaries are found by counting quotes, so f must not contain bare quotes
+// in unquoted fields. The io.Reader passed to NewDecoder is not used.
+func (d *Decoder) DecodeFileParallel(f io.ReaderAt, size int64, v interface{}) error {
+	var vv reflect.Value
+	vv, err := valueOf(v)
+	if err != nil {
+		return err
+	}
+
+	structType := sliceStructType(vv.Type())
+	if structType == nil {
+		return ErrInvalidArgument
+	}
+
+	// record boundaries are found by looking for single byte quotes and newlines
+	if d.charset.isUTF16() {
+		return fmt.Errorf("csv: DecodeFileParallel does not support %s", d.charset)
+	}
+
+	// skip the preamble, which consists of whole lines in all supported charsets
+	if _, _, err := d.readPreamble(d.charset.NewDecoder(io.NewSectionReader(f, 0, size))); err != nil {
+		return err
+	}
+
+	var start int64
+	if len(d.preamble) > 0 {
+		if start, err = skipLinesAt(f, size, len(d.preamble)); err != nil {
+			return err
+		}
+	} else if d.charset == UTF8 {
+		// skip the byte order mark for all Readers
+		buf := make([]byte, len(bom))
+		if n, err := f.ReadAt(buf, 0); string(buf[:n]) == bom {
+			start = int64(n)
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	if d.autoDetect {
+		// the detected dialect applies to this call only
+		if d, err = d.detectDialect(d.charset.NewDecoder(io.NewSectionReader(f, start, size-start))); err != nil {
+			return err
+		}
+	}
+
+	workers := d.concurrency
+	if workers <= 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// read the header from the first record
+	line := len(d.preamble) + 1
+	var header []string
+	if d.skipHeader {
+		if header, err = d.structHeader(structType); err != nil {
+			return err
+		}
+	} else {
+		end, nl, err := nextBoundary(f, size, start, false)
+		if err != nil {
+			return err
+		} else if header, err = d.newReader(d.charset.NewDecoder(io.NewSectionReader(f, start, end-start))).Read(); err != nil {
+			return err
+		}
+		start = end
+		line += nl
+	}
+
+	chunks, err := splitChunks(f, start, size, line, workers)
+	if err != nil {
+		return err
+	}
+
+	obs := newObservation(d.observer, d.observeInterval)
+	defer obs.done()
+
+	rows := make([]int64, len(chunks)) // number of rows read by each chunk
+	slices := make([]reflect.Value, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(chunks))
+	for i, c := range chunks {
+		go func(i int, c chunk) {
+			defer wg.Done()
+			r := &lineOffsetReader{
+				Reader: d.newReader(d.charset.NewDecoder(obs.reader(io.NewSectionReader(f, c.start, c.end-c.start)))),
+				offset: c.line - 1,
+			}
+			var state decodeState
+			if errs[i] = state.setup(d, r, structType, header); errs[i] == nil {
+				state.rows = &rows[i]
+				state.obs = obs
+				slices[i], errs[i] = state.decodeAll(reflect.MakeSlice(vv.Elem().Type(), 0, 0))
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	// the chunks enforce MaxRows on their own rows,
+	// so the row that exceeds the limit is found by adding them up
+	var total, read int
+	for i, c := range chunks {
+		if max := d.limits.MaxRows; max > 0 && read+int(rows[i]) > max {
+			return d.rowLimitError(f, c, max-read)
+		} else if errs[i] != nil {
+			return errs[i]
+		}
+		read += int(rows[i])
+		total += slices[i].Len()
+	}
+
+	slice := reflect.MakeSlice(vv.Elem().Type(), 0, total)
+	for _, s := range slices {
+		slice = reflect.AppendSlice(slice, s)
+	}
+
+	vv.Elem().Set(slice) // *v = slice
+	return nil
+}
+
+// rowLimitError returns the error of the n-th row of chunk c
+// exceeding the MaxRows limit, counting from zero.
+func (d *Decoder) rowLimitError(f io.ReaderAt, c chunk, n int) error {
+	r := &lineOffsetReader{
+		Reader: d.newReader(d.charset.NewDecoder(io.NewSectionReader(f, c.start, c.end-c.start))),
+		offset: c.line - 1,
+	}
+	for ; n >= 0; n-- {
+		if _, err := r.Read(); err != nil {
+			return fmt.Errorf("csv: %w", err)
+		}
+	}
+	line, _ := fieldPos(r, 0)
+	return fmt.Errorf("csv: %w", &LimitError{Limit: "rows", Max: d.limits.MaxRows, Line: line})
+}
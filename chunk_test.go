@@ -0,0 +1,113 @@
+package csvbuddy
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitChunks(t *testing.T) {
+	// every record spans two lines because of a quoted newline
+	var sb strings.Builder
+	for i := 0; sb.Len() < 4*minChunkSize; i++ {
+		fmt.Fprintf(&sb, "%d,\"a\nb\"\n", i)
+	}
+	data := sb.String()
+	r := strings.NewReader(data)
+
+	chunks, err := splitChunks(r, 0, r.Size(), 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(chunks) != 4 {
+		t.Fatal("expected 4 chunks", len(chunks))
+	}
+
+	var end int64
+	for _, c := range chunks {
+		if c.start != end {
+			t.Fatal("chunks are not contiguous", chunks)
+		} else if c.line != 1+strings.Count(data[:c.start], "\n") {
+			t.Fatal("wrong line number", c)
+		} else if c.line%2 != 1 || !strings.HasSuffix(data[:c.start], "\"\n") && c.start > 0 {
+			t.Fatal("chunk does not start at a record", c)
+		}
+		end = c.end
+	}
+
+	if end != r.Size() {
+		t.Fatal("chunks do not cover the file")
+	}
+}
+
+func TestDecodeFileParallel(t *testing.T) {
+	testdata := parallelData(20000)
+
+	var expect []parallelRow
+	if err := Unmarshal([]byte(testdata), &expect); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 3, 8} {
+		var data []parallelRow
+		d := NewDecoder(nil)
+		d.SetConcurrency(n)
+		f := strings.NewReader(testdata)
+		if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, expect) {
+			t.Fatal("not equal", n)
+		}
+	}
+}
+
+func TestDecodeFileParallelSkipHeader(t *testing.T) {
+	testdata := parallelData(10)
+	testdata = testdata[strings.IndexByte(testdata, '\n')+1:]
+
+	var data []parallelRow
+	d := NewDecoder(nil)
+	d.SkipHeader()
+	f := strings.NewReader(testdata)
+	if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 10 || data[9].S != "row 9" {
+		t.Fatal("unexpected rows", data)
+	}
+}
+
+func TestDecodeFileParallelFirstError(t *testing.T) {
+	testdata := parallelData(20000, 15000, 5000)
+
+	var data []parallelRow
+	f := strings.NewReader(testdata)
+	d := NewDecoder(nil)
+	d.SetConcurrency(4)
+
+	var pe *csv.ParseError
+	if err := d.DecodeFileParallel(f, f.Size(), &data); !errors.As(err, &pe) {
+		t.Fatal("expected ParseError", err)
+	} else if pe.Line != 5002 || pe.Column != 6 {
+		t.Fatal("expected first error on line 5002", err)
+	} else if data != nil {
+		t.Fatal("should not be assigned on error")
+	}
+}
+
+func TestDecodeFileParallelReaderError(t *testing.T) {
+	testdata := parallelData(20000) + "1,2,\"x\"y\n"
+
+	var data []parallelRow
+	f := strings.NewReader(testdata)
+	d := NewDecoder(nil)
+	d.SetConcurrency(4)
+
+	var pe *csv.ParseError
+	if err := d.DecodeFileParallel(f, f.Size(), &data); !errors.As(err, &pe) {
+		t.Fatal("expected ParseError", err)
+	} else if pe.Line != 20002 {
+		t.Fatal("expected error on line 20002", err)
+	}
+}
@@ -0,0 +1,519 @@
+// Command csvbuddy-gen generates reflection-free implementations of
+// csvbuddy.CSVRecordMarshaler and csvbuddy.CSVRecordUnmarshaler for struct types.
+// The Decoder and Encoder use the generated methods automatically.
+//
+// Usage:
+//
+//	csvbuddy-gen -type=T1,T2 [-output=file] [dir]
+//
+// The types are read from the package in dir, which defaults to the current directory.
+// The output is written to dir/csvbuddy_gen.go unless -output is given.
+// Typically it is invoked by a go:generate directive:
+//
+//	//go:generate csvbuddy-gen -type=Person
+//
+// The generated code honors the name, "-", inline, base, prec, fmt and bool tag options,
+// optional (pointer) fields, encoding.TextMarshaler and csvbuddy.CSVFieldMarshaler.
+// It implements csvbuddy.CSVColumnCounter and reports conversion errors as
+// csvbuddy.ColumnError, so that the Decoder reports the same errors as for reflected types.
+// Types with oneof, min, max, minlen, maxlen or pattern options are rejected because
+// the generated code bypasses validation. The Decoder and Encoder reject generated types
+// with fields of a type registered with csvbuddy.RegisterEnum, and with bool fields
+// without the bool tag option if SetBoolFormat is used, because the generated code
+// cannot honor them.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/askeladdk/csvbuddy"
+)
+
+const defaultOutput = "csvbuddy_gen.go"
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names; must be set")
+	output := flag.String("output", "", "output file name; default dir/"+defaultOutput)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: csvbuddy-gen -type=T1,T2 [-output=file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	if *output == "" {
+		*output = filepath.Join(dir, defaultOutput)
+	}
+
+	src, err := generate(dir, strings.Split(*typeNames, ","), filepath.Base(*output))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "csvbuddy-gen:", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "csvbuddy-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// loadPackage parses and type checks the package in dir,
+// skipping test files and the previously generated output.
+func loadPackage(dir, output string) (*types.Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if name := filepath.Base(path); strings.HasSuffix(name, "_test.go") || name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	// type errors are ignored because the package may refer
+	// to methods that have not been generated yet
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	return pkg, nil
+}
+
+// generate returns the formatted source code of the marshalers of typeNames.
+func generate(dir string, typeNames []string, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	g := generator{
+		pkg:     pkg,
+		imports: map[string]string{},
+	}
+
+	for _, name := range typeNames {
+		if err := g.generateType(strings.TrimSpace(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by csvbuddy-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// standard library packages first
+	sort.SliceStable(paths, func(i, j int) bool {
+		return !strings.Contains(paths[i], ".") && strings.Contains(paths[j], ".")
+	})
+
+	fmt.Fprintf(&src, "import (\n")
+	for i, path := range paths {
+		if i > 0 && strings.Contains(path, ".") && !strings.Contains(paths[i-1], ".") {
+			fmt.Fprintf(&src, "\n")
+		}
+		if name := g.imports[path]; name != pathpkg.Base(path) {
+			fmt.Fprintf(&src, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&src, ")\n\n")
+	src.Write(g.vars.Bytes())
+	src.Write(g.body.Bytes())
+
+	return format.Source(src.Bytes())
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string // package path => name
+	vars    bytes.Buffer      // package-level variables
+	body    bytes.Buffer      // methods
+}
+
+// field is a struct field that maps to a CSV column.
+type field struct {
+	Expr string     // selector expression relative to the receiver
+	Type types.Type // field type
+	Tag  csvbuddy.FieldTag
+	Raw  string // csv struct field tag with the resolved name
+}
+
+func (g *generator) use(path, name string) string {
+	g.imports[path] = name
+	return name
+}
+
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	return g.use(p.Path(), p.Name())
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// appendFields mirrors how csvbuddy maps struct fields to columns.
+func appendFields(st *types.Struct, expr string, fields []field, names map[string]bool) ([]field, error) {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+
+		tag := reflect.StructTag(st.Tag(i)).Get("csv")
+
+		// check for inline struct
+		if inner, ok := v.Type().Underlying().(*types.Struct); ok {
+			if v.Anonymous() || strings.Contains(tag, ",inline") {
+				var err error
+				if fields, err = appendFields(inner, expr+"."+v.Name(), fields, names); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		parsed := csvbuddy.ParseTag(tag)
+		if parsed.Name == "" {
+			parsed.Name = v.Name()
+			tag = v.Name() + tag
+		}
+		if parsed.Name == "-" {
+			continue
+		}
+
+		if names[parsed.Name] {
+			return nil, fmt.Errorf("duplicate field name '%s'", parsed.Name)
+		} else if parsed.OneOf != nil || parsed.Min != "" || parsed.Max != "" ||
+			parsed.MinLen >= 0 || parsed.MaxLen >= 0 || parsed.Pattern != "" {
+			return nil, fmt.Errorf("field '%s': validation tag options are not supported", parsed.Name)
+		}
+
+		names[parsed.Name] = true
+		fields = append(fields, field{
+			Expr: expr + "." + v.Name(),
+			Type: v.Type(),
+			Tag:  parsed,
+			Raw:  tag,
+		})
+	}
+	return fields, nil
+}
+
+func (g *generator) generateType(name string) error {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found", name)
+	}
+
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", name)
+	}
+
+	fields, err := appendFields(st, "x", nil, map[string]bool{})
+	if err != nil {
+		return fmt.Errorf("type %s: %w", name, err)
+	}
+
+	var enc, dec bytes.Buffer
+	for i, f := range fields {
+		conv, err := g.converterOf(f, fmt.Sprintf("csvbuddyTag%s%d", name, i))
+		if err != nil {
+			return fmt.Errorf("type %s: field '%s': %w", name, f.Tag.Name, err)
+		}
+		fmt.Fprintf(&enc, "case %q:\n%s\n", f.Tag.Name, conv.encode(f.Expr))
+		fmt.Fprintf(&dec, "case %q:\n%s\n", f.Tag.Name, conv.decode(f.Expr))
+	}
+
+	csvbuddy := g.use("github.com/askeladdk/csvbuddy", "csvbuddy")
+
+	fmt.Fprintf(&g.body, `// CSVColumnCount implements csvbuddy.CSVColumnCounter.
+func (x *%[1]s) CSVColumnCount() int {
+	return %[5]d
+}
+
+// MarshalCSVRecord implements csvbuddy.CSVRecordMarshaler.
+func (x *%[1]s) MarshalCSVRecord(header []string) ([]string, error) {
+	var err error
+	record := make([]string, len(header))
+	for i, h := range header {
+		switch h {
+		%[2]s}
+		if err != nil {
+			return nil, &%[4]s.ColumnError{Column: i, Name: h, Err: err}
+		}
+	}
+	return record, nil
+}
+
+// UnmarshalCSVRecord implements csvbuddy.CSVRecordUnmarshaler.
+func (x *%[1]s) UnmarshalCSVRecord(header, record []string) error {
+	var err error
+	for i, h := range header {
+		var s string
+		if i < len(record) {
+			s = record[i]
+		}
+		switch h {
+		%[3]s}
+		if err != nil {
+			return &%[4]s.ColumnError{Column: i, Name: h, Err: err}
+		}
+	}
+	return nil
+}
+
+`, name, enc.String(), dec.String(), csvbuddy, len(fields))
+	return nil
+}
+
+// converter produces statements that convert between a field and a string.
+// The encode statements assign record[i] and err, the decode statements read s and assign err.
+// The addr argument is an expression whose methods have pointer receivers,
+// and val or lval is an expression of the field value.
+type converter struct {
+	ptr    bool // the field is optional
+	elem   string
+	encodf func(addr, val string) string
+	decodf func(addr, lval string) string
+}
+
+func (c *converter) encode(expr string) string {
+	if c.ptr {
+		return fmt.Sprintf("if p := %s; p != nil {\n%s\n}", expr, c.encodf("p", "*p"))
+	}
+	return c.encodf(expr, expr)
+}
+
+func (c *converter) decode(expr string) string {
+	if c.ptr {
+		return fmt.Sprintf("if s != \"\" {\np := new(%s)\n%s\n%s = p\n}", c.elem, c.decodf("p", "*p"), expr)
+	}
+	return c.decodf(expr, expr)
+}
+
+// convert returns expr converted to the element type, which is a no-op if the type is from.
+func (c *converter) convert(from, expr string) string {
+	if c.elem == from {
+		return expr
+	}
+	return c.elem + "(" + expr + ")"
+}
+
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func (g *generator) converterOf(f field, tagVar string) (*converter, error) {
+	t := f.Type
+	c := converter{}
+
+	if p, ok := t.(*types.Pointer); ok {
+		if _, ok := p.Elem().(*types.Pointer); ok {
+			return nil, errors.New("pointers to pointers are not supported")
+		}
+		t, c.ptr = p.Elem(), true
+	}
+
+	c.elem = g.typeString(t)
+	tag := f.Tag
+
+	// methods in order of precedence
+	var encodf, decodf func(addr, val string) string
+	marshaler, unmarshaler := "encoding.TextMarshaler", "encoding.TextUnmarshaler"
+	if hasMethod(t, "MarshalCSVField") || hasMethod(t, "UnmarshalCSVField") {
+		marshaler, unmarshaler = "csvbuddy.CSVFieldMarshaler", "csvbuddy.CSVFieldUnmarshaler"
+		fmt.Fprintf(&g.vars, "var %s = %s.ParseTag(%q)\n\n", tagVar, g.use("github.com/askeladdk/csvbuddy", "csvbuddy"), f.Raw)
+		if hasMethod(t, "MarshalCSVField") {
+			encodf = func(addr, _ string) string {
+				return fmt.Sprintf("record[i], err = %s.MarshalCSVField(%s)", addr, tagVar)
+			}
+		}
+		if hasMethod(t, "UnmarshalCSVField") {
+			decodf = func(addr, _ string) string {
+				return fmt.Sprintf("err = %s.UnmarshalCSVField(%s, s)", addr, tagVar)
+			}
+		}
+	}
+	if encodf == nil && hasMethod(t, "MarshalText") {
+		encodf = func(addr, _ string) string {
+			return fmt.Sprintf("var b []byte\nif b, err = %s.MarshalText(); err == nil {\nrecord[i] = string(b)\n}", addr)
+		}
+	}
+	if decodf == nil && hasMethod(t, "UnmarshalText") {
+		decodf = func(addr, _ string) string {
+			return fmt.Sprintf("err = %s.UnmarshalText([]byte(s))", addr)
+		}
+	}
+	if encodf != nil || decodf != nil {
+		errs := g.use("errors", "errors")
+		if encodf == nil {
+			encodf = func(_, _ string) string {
+				return fmt.Sprintf("err = %s.New(\"value does not implement %s\")", errs, marshaler)
+			}
+		}
+		if decodf == nil {
+			decodf = func(_, _ string) string {
+				return fmt.Sprintf("err = %s.New(\"value does not implement %s\")", errs, unmarshaler)
+			}
+		}
+		c.encodf, c.decodf = encodf, decodf
+		return &c, nil
+	}
+
+	if sl, ok := t.Underlying().(*types.Slice); ok {
+		if b, ok := sl.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			c.encodf = func(_, val string) string {
+				return fmt.Sprintf("record[i] = string(%s)", val)
+			}
+			c.decodf = func(_, lval string) string {
+				return fmt.Sprintf("%s = %s", lval, c.elem+"(s)")
+			}
+			return &c, nil
+		}
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Kind() == types.Uintptr {
+		return nil, fmt.Errorf("unsupported type %s", c.elem)
+	}
+
+	sc := "strconv"
+	if basic.Info()&types.IsString == 0 {
+		g.use(sc, sc)
+	}
+
+	info := basic.Info()
+	bits := bitSize(basic)
+
+	switch {
+	case info&types.IsBoolean != 0 && tag.Bool != nil:
+		strs := g.use("strings", "strings")
+		t, f := tag.Bool.True[0], tag.Bool.False[0]
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("if %s {\nrecord[i] = %q\n} else {\nrecord[i] = %q\n}", val, t, f)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf(`switch {
+case %[1]s.EqualFold(s, %[3]q):
+	%[2]s = true
+case %[1]s.EqualFold(s, %[4]q):
+	%[2]s = false
+default:
+	err = &%[5]s.NumError{Func: "ParseBool", Num: s, Err: %[5]s.ErrSyntax}
+}`, strs, lval, t, f, sc)
+		}
+	case info&types.IsBoolean != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = %s.FormatBool(bool(%s))", sc, val)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("var b bool\nif b, err = %s.ParseBool(s); err == nil {\n%s = %s\n}", sc, lval, c.convert("bool", "b"))
+		}
+	case info&types.IsInteger != 0 && info&types.IsUnsigned != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = %s.FormatUint(uint64(%s), %d)", sc, val, tag.Base)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("var n uint64\nif n, err = %s.ParseUint(s, %d, %s); err == nil {\n%s = %s\n}", sc, tag.Base, bits, lval, c.convert("uint64", "n"))
+		}
+	case info&types.IsInteger != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = %s.FormatInt(int64(%s), %d)", sc, val, tag.Base)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("var n int64\nif n, err = %s.ParseInt(s, %d, %s); err == nil {\n%s = %s\n}", sc, tag.Base, bits, lval, c.convert("int64", "n"))
+		}
+	case info&types.IsFloat != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = %s.FormatFloat(float64(%s), %q, %d, %s)", sc, val, tag.Fmt, tag.Prec, bits)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("var f float64\nif f, err = %s.ParseFloat(s, %s); err == nil {\n%s = %s\n}", sc, bits, lval, c.convert("float64", "f"))
+		}
+	case info&types.IsComplex != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = %s.FormatComplex(complex128(%s), %q, %d, %s)", sc, val, tag.Fmt, tag.Prec, bits)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("var c complex128\nif c, err = %s.ParseComplex(s, %s); err == nil {\n%s = %s\n}", sc, bits, lval, c.convert("complex128", "c"))
+		}
+	case info&types.IsString != 0:
+		c.encodf = func(_, val string) string {
+			return fmt.Sprintf("record[i] = string(%s)", val)
+		}
+		c.decodf = func(_, lval string) string {
+			return fmt.Sprintf("%s = %s", lval, c.convert("string", "s"))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", c.elem)
+	}
+
+	return &c, nil
+}
+
+// bitSize returns the bit size argument of the strconv functions for t.
+func bitSize(t *types.Basic) string {
+	switch t.Kind() {
+	case types.Int8, types.Uint8:
+		return "8"
+	case types.Int16, types.Uint16:
+		return "16"
+	case types.Int32, types.Uint32, types.Float32:
+		return "32"
+	case types.Int64, types.Uint64, types.Float64, types.Complex64:
+		return "64"
+	case types.Complex128:
+		return "128"
+	}
+	return "strconv.IntSize"
+}
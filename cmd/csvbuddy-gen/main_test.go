@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func copyDir(t *testing.T, dst, src string) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	copyDir(t, dir, "testdata/rows")
+
+	gomod := "module rows\n\ngo 1.17\n\n" +
+		"require github.com/askeladdk/csvbuddy v0.0.0\n\n" +
+		"replace github.com/askeladdk/csvbuddy => " + root + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, []string{"Row", "Flags"}, defaultOutput)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := os.WriteFile(filepath.Join(dir, defaultOutput), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal(err, string(out), string(src))
+	}
+
+	expect := strings.Join([]string{
+		"name,flt,flag,opt,bytes,upper,time,c,hex,Emb,Money",
+		"a,3.142E+00,Y,1,xy,u,2021-01-02T03:04:05Z,(1+2i),ff,-1,Money:$$",
+		"b,1.000E+02,N,,,,,(0+0i),0,0,Money:",
+		"a 1.5 true 7 xy UP 2021-01-02T03:04:05Z (1+2i) 255 -3",
+		"b 0 false   (0+0i) 0 0",
+		"csv: parse error on line 2, column 3: strconv.ParseBool: parsing \"maybe\": invalid syntax",
+		"csv: record on line 2: wrong number of fields",
+		"csv: generated code of field 'on' ignores the BoolFormat; add the bool tag option",
+		"",
+	}, "\n")
+
+	if string(out) != expect {
+		t.Fatal(string(out))
+	}
+}
+
+func TestGenerateRejectsValidation(t *testing.T) {
+	dir := t.TempDir()
+	src := "package x\n\ntype T struct {\n\tA int `csv:\"a,min=1\"`\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "x.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := generate(dir, []string{"T"}, defaultOutput); err == nil {
+		t.Fatal("expected error")
+	}
+}
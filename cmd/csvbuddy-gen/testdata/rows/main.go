@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/askeladdk/csvbuddy"
+)
+
+var (
+	_ csvbuddy.CSVRecordMarshaler   = (*Row)(nil)
+	_ csvbuddy.CSVRecordUnmarshaler = (*Row)(nil)
+	_ csvbuddy.CSVColumnCounter     = (*Row)(nil)
+)
+
+func main() {
+	one := 1
+	ts := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []Row{
+		{"a", 1, 3.14159, true, &one, []byte("xy"), "u", &ts, 1 + 2i, Inner{255}, Embedded{-1}, 2},
+		{"b", 2, 100, false, nil, nil, "", nil, 0, Inner{}, Embedded{}, 0},
+	}
+
+	text, err := csvbuddy.Marshal(&rows)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(string(text))
+
+	input := "name,flt,flag,opt,bytes,upper,time,c,hex,Emb\n" +
+		"a,1.5,y,7,xy,up,2021-01-02T03:04:05Z,(1+2i),ff,-3\n" +
+		"b,0,N,,,,,0,0,0\n"
+
+	var decoded []Row
+	if err := csvbuddy.Unmarshal([]byte(input), &decoded); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, r := range decoded {
+		fmt.Printf("%s %v %v", r.Name, r.Flt, r.Flag)
+		if r.Opt != nil {
+			fmt.Printf(" %d", *r.Opt)
+		}
+		fmt.Printf(" %s %s", r.Bytes, r.Upper)
+		if r.Time != nil {
+			fmt.Printf(" %s", r.Time.Format(time.RFC3339))
+		}
+		fmt.Printf(" %v %d %d\n", r.C, r.In.Hex, r.Emb)
+	}
+
+	if err := csvbuddy.Unmarshal([]byte("name,flag\na,maybe\n"), &decoded); err != nil {
+		fmt.Println(err)
+	}
+
+	d := csvbuddy.NewDecoder(strings.NewReader("name,flag\na,Y\n"))
+	d.DisallowShortFields()
+	if err := d.Decode(&decoded); err != nil {
+		fmt.Println(err)
+	}
+
+	var flags []Flags
+	d = csvbuddy.NewDecoder(strings.NewReader("on\nyes\n"))
+	d.SetBoolFormat(csvbuddy.BoolFormat{True: []string{"yes"}, False: []string{"no"}})
+	if err := d.Decode(&flags); err != nil {
+		fmt.Println(err)
+	}
+}
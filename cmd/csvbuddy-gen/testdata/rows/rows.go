@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/askeladdk/csvbuddy"
+)
+
+type upper string
+
+func (u *upper) UnmarshalText(b []byte) error {
+	*u = upper(strings.ToUpper(string(b)))
+	return nil
+}
+
+func (u *upper) MarshalText() ([]byte, error) {
+	return []byte(*u), nil
+}
+
+type money int64
+
+func (m *money) MarshalCSVField(tag csvbuddy.FieldTag) (string, error) {
+	unit, _ := tag.Lookup("unit")
+	return tag.Name + ":" + strings.Repeat(unit, int(*m)), nil
+}
+
+type Inner struct {
+	Hex uint16 `csv:"hex,base=16"`
+}
+
+type Embedded struct {
+	Emb int8
+}
+
+type Flags struct {
+	On bool `csv:"on"`
+}
+
+type Row struct {
+	Name    string     `csv:"name"`
+	Ignored int        `csv:"-"`
+	Flt     float64    `csv:"flt,prec=3,fmt=E"`
+	Flag    bool       `csv:"flag,bool=Y|N"`
+	Opt     *int       `csv:"opt"`
+	Bytes   []byte     `csv:"bytes"`
+	Upper   upper      `csv:"upper"`
+	Time    *time.Time `csv:"time"`
+	C       complex64  `csv:"c"`
+	In      Inner      `csv:",inline"`
+	Embedded
+	Money money `csv:",unit=$"`
+}
@@ -0,0 +1,150 @@
+package csvbuddy
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// setter converts a string and stores it at a field address.
+type setter func(unsafe.Pointer, string) error
+
+// compiledField is a structField bound to a column of the header.
+type compiledField struct {
+	*structField
+	Column int          // column index in the record
+	Type   reflect.Type // struct field type
+	Offset uintptr      // byte offset of the struct field in the row
+	set    setter       // fast path, nil if the converter must be used
+	str    bool         // the field is a string that may be stored in an arena
+}
+
+// value returns the field of the row at base as an addressable reflect.Value.
+func (f *compiledField) value(base unsafe.Pointer) reflect.Value {
+	return reflect.NewAt(f.Type, unsafe.Add(base, f.Offset)).Elem()
+}
+
+// compileFields binds fields to their columns using the
+// (column index, struct field index) pairs of indices.
+func compileFields(structType reflect.Type, fields []structField, indices []int) []compiledField {
+	compiled := make([]compiledField, 0, len(indices)/2)
+	for i := 0; i < len(indices); i += 2 {
+		field := &fields[indices[i+1]]
+
+		// walk the index path to find the type and offset
+		t, offset := structType, uintptr(0)
+		for _, j := range field.Index {
+			sf := t.Field(j)
+			t, offset = sf.Type, offset+sf.Offset
+		}
+
+		_, str := field.converter.(*stringCodec)
+
+		compiled = append(compiled, compiledField{
+			structField: field,
+			Column:      indices[i],
+			Type:        t,
+			Offset:      offset,
+			set:         compileSetter(t, field.converter),
+			str:         str,
+		})
+	}
+	return compiled
+}
+
+// compileSetter returns a setter for the basic codecs that
+// do not need reflection, and nil for all others.
+func compileSetter(t reflect.Type, c converter) setter {
+	switch c := c.(type) {
+	case *boolCodec:
+		return func(p unsafe.Pointer, s string) (err error) {
+			var x bool
+			if c.Format == nil {
+				x, err = strconv.ParseBool(s)
+			} else {
+				x, err = c.Format.parse(s)
+			}
+			if err == nil {
+				*(*bool)(p) = x
+			}
+			return
+		}
+	case *floatCodec:
+		if t.Kind() == reflect.Float32 {
+			return func(p unsafe.Pointer, s string) error {
+				x, err := strconv.ParseFloat(s, 32)
+				if err == nil {
+					*(*float32)(p) = float32(x)
+				}
+				return err
+			}
+		}
+		return func(p unsafe.Pointer, s string) error {
+			x, err := strconv.ParseFloat(s, 64)
+			if err == nil {
+				*(*float64)(p) = x
+			}
+			return err
+		}
+	case *intCodec:
+		size := t.Size()
+		return func(p unsafe.Pointer, s string) error {
+			x, err := strconv.ParseInt(s, c.Base, c.BitSize)
+			if err == nil {
+				storeInt(p, size, uint64(x))
+			}
+			return err
+		}
+	case *uintCodec:
+		size := t.Size()
+		return func(p unsafe.Pointer, s string) error {
+			x, err := strconv.ParseUint(s, c.Base, c.BitSize)
+			if err == nil {
+				storeInt(p, size, x)
+			}
+			return err
+		}
+	case *stringCodec:
+		return func(p unsafe.Pointer, s string) error {
+			*(*string)(p) = s
+			return nil
+		}
+	}
+	return nil
+}
+
+// storeInt stores the lower size bytes of x at p.
+func storeInt(p unsafe.Pointer, size uintptr, x uint64) {
+	switch size {
+	case 1:
+		*(*uint8)(p) = uint8(x)
+	case 2:
+		*(*uint16)(p) = uint16(x)
+	case 4:
+		*(*uint32)(p) = uint32(x)
+	default:
+		*(*uint64)(p) = x
+	}
+}
+
+// arena copies strings into shared blocks of memory
+// to amortize allocations over many strings.
+type arena struct {
+	buf  []byte
+	size int
+}
+
+func (a *arena) string(s string) string {
+	if len(s) == 0 {
+		return ""
+	} else if len(s) > a.size {
+		return string([]byte(s))
+	} else if cap(a.buf)-len(a.buf) < len(s) {
+		a.buf = make([]byte, 0, a.size)
+	}
+	// the bytes are never modified after they have been appended
+	start := len(a.buf)
+	a.buf = append(a.buf, s...)
+	b := a.buf[start:len(a.buf):len(a.buf)]
+	return *(*string)(unsafe.Pointer(&b))
+}
@@ -0,0 +1,132 @@
+package csvbuddy
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// repeatReader returns the same record n times without allocating.
+type repeatReader struct {
+	record []string
+	n      int
+}
+
+func (r *repeatReader) Read() ([]string, error) {
+	if r.n == 0 {
+		return nil, io.EOF
+	}
+	r.n--
+	return r.record, nil
+}
+
+func TestCompileFields(t *testing.T) {
+	type Inner struct {
+		C int16
+		D string
+	}
+
+	type struc struct {
+		A int8
+		B float32
+		Inner
+		E *int
+	}
+
+	fields, err := structFieldsOf(reflect.TypeOf(struc{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiled := compileFields(reflect.TypeOf(struc{}), fields, []int{0, 4, 1, 3, 2, 0})
+
+	var x struc
+	if len(compiled) != 3 {
+		t.Fatal(compiled)
+	} else if f := compiled[0]; f.Name != "E" || f.Offset != unsafe.Offsetof(x.E) || f.set != nil {
+		t.Error("E", f.Offset)
+	} else if f := compiled[1]; f.Name != "D" || f.Offset != unsafe.Offsetof(x.Inner)+unsafe.Offsetof(x.Inner.D) || !f.str {
+		t.Error("D", f.Offset)
+	} else if f := compiled[2]; f.Name != "A" || f.Column != 2 || f.set == nil {
+		t.Error("A", f.Column)
+	}
+}
+
+func TestDecodeNumericZeroAllocs(t *testing.T) {
+	type struc struct {
+		A int
+		B int8
+		C uint16
+		D float32
+		E float64
+		F bool
+	}
+
+	r := &repeatReader{[]string{"-1", "-2", "3", "4.5", "6.75", "true"}, 1000}
+
+	d := NewDecoder(nil)
+	d.SkipHeader()
+	d.SetReaderFunc(func(io.Reader) Reader { return r })
+
+	var row struc
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := testing.AllocsPerRun(100, func() { iter.Scan() }); n != 0 {
+		t.Error("allocations per row:", n)
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	} else if row != (struc{-1, -2, 3, 4.5, 6.75, true}) {
+		t.Fatal(row)
+	}
+}
+
+func TestDecodeArena(t *testing.T) {
+	type struc struct {
+		A string
+		B string
+	}
+
+	testdata := "A,B\nhello,world\n,this string is longer than the arena\n"
+
+	var data []struc
+
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SetArenaSize(16)
+
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []struc{
+		{"hello", "world"},
+		{"", "this string is longer than the arena"},
+	}
+
+	if !reflect.DeepEqual(data, expect) {
+		t.Fatal(data)
+	}
+}
+
+func TestArena(t *testing.T) {
+	a := arena{size: 8}
+	s1 := a.string("abc")
+	s2 := a.string("defgh")
+	s3 := a.string("ijk")
+	if s1 != "abc" || s2 != "defgh" || s3 != "ijk" {
+		t.Fatal(s1, s2, s3)
+	}
+
+	data := func(s string) uintptr { return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data }
+	if data(s2) != data(s1)+3 {
+		t.Error("expected s1 and s2 to share a block")
+	} else if len(a.buf) != 3 || data(s3) != uintptr(unsafe.Pointer(&a.buf[0])) {
+		t.Error("expected s3 to be in a new block")
+	}
+}
@@ -1,12 +1,15 @@
 package csvbuddy
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"unsafe"
 )
 
 // ErrInvalidArgument signals that an interface{} argument is of an invalid type.
@@ -17,6 +20,23 @@ type Decoder struct {
 	reader                io.Reader
 	readerFunc            ReaderFunc
 	mapFunc               MapFunc
+	boolFormat            *BoolFormat
+	arenaSize             int
+	concurrency           int
+	capacityHint          int
+	limits                Limits
+	customReader          bool
+	readerFuncSet         bool    // readerFunc was set by SetReaderFunc or SetFixedWidth
+	dialect               Dialect // dialect of the default Reader
+	checkpoint            *Checkpoint
+	autoDetect            bool
+	charset               Charset
+	observer              Observer
+	observeInterval       int
+	header                []string
+	skipLines             int
+	skipUntil             func(line string) bool
+	preamble              []string
 	disallowUnknownFields bool
 	disallowShortFields   bool
 	skipHeader            bool
@@ -29,47 +49,86 @@ func Unmarshal(data []byte, v interface{}) error {
 }
 
 // NewDecoder returns a Decoder that reads from r.
+// Records are read by a FastReader using comma as the delimiter.
 func NewDecoder(r io.Reader) *Decoder {
+	dialect := Dialect{Comma: ','}
 	return &Decoder{
 		reader:     r,
-		readerFunc: NewReader,
+		readerFunc: dialect.NewReader,
+		dialect:    dialect,
 		mapFunc:    func(_, v string) string { return v },
 	}
 }
 
 // Decode decodes a CSV as a slice of structs and stores it in v.
-// The value of v must be a pointer to a slice of structs.
+// The value of v must be a pointer to a slice of structs
+// or a pointer to a slice of pointers to structs.
 func (d *Decoder) Decode(v interface{}) error {
+	return d.decodeSlice(context.Background(), v, false)
+}
+
+// DecodeContext is like Decode but stops when ctx is done.
+// The returned error wraps ctx.Err() and reports the line reached.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	return d.decodeSlice(ctx, v, false)
+}
+
+// DecodeAppend is like Decode but appends the rows to the slice that v points to
+// with the semantics of the append built-in: rows are decoded into the spare capacity
+// of the slice if it has room, or into a new array otherwise.
+// The slice that v points to is not replaced if an error occurs,
+// but the elements beyond its length may have been overwritten.
+func (d *Decoder) DecodeAppend(v interface{}) error {
+	return d.decodeSlice(context.Background(), v, true)
+}
+
+func (d *Decoder) decodeSlice(ctx context.Context, v interface{}, appending bool) error {
 	var vv reflect.Value
 	vv, err := valueOf(v)
 	if err != nil {
 		return err
 	}
 
-	structType := innerTypeOf(vv.Type(), reflect.Ptr, reflect.Slice, reflect.Struct)
+	structType := sliceStructType(vv.Type())
 	if structType == nil {
 		return ErrInvalidArgument
 	}
 
-	slice := reflect.MakeSlice(vv.Elem().Type(), 0, 0) // make([]T)
-
 	var state decodeState
 
-	if err := state.init(d, structType); err != nil {
+	err = state.init(d, structType)
+	defer state.obs.done()
+	if err != nil {
 		return err
 	}
 
-	for {
-		value, err := state.next()
-		if err == io.EOF {
-			vv.Elem().Set(slice) // *v = slice
-			return nil
-		} else if err != nil {
-			return err
+	state.done = ctx.Done()
+	state.ctx = ctx
+
+	var slice reflect.Value
+	if appending {
+		slice = vv.Elem()
+		if n := slice.Len(); slice.Cap()-n < d.capacityHint {
+			grown := reflect.MakeSlice(slice.Type(), n, n+d.capacityHint)
+			reflect.Copy(grown, slice)
+			slice = grown
 		}
+	} else {
+		slice = reflect.MakeSlice(vv.Elem().Type(), 0, d.capacityHint) // make([]T, 0, hint)
+	}
 
-		slice = reflect.Append(slice, value.Elem()) // append(slice, *value)
+	if d.concurrency > 1 {
+		slice, err = newPipeline(&state, d.concurrency).decodeAll(slice)
+	} else {
+		slice, err = state.decodeAll(slice)
+	}
+
+	if err != nil {
+		return err
 	}
+
+	vv.Elem().Set(slice) // *v = slice
+	return nil
 }
 
 // DisallowUnknownFields causes the Decoder to raise an error
@@ -84,14 +143,63 @@ func (d *Decoder) DisallowShortFields() { d.disallowShortFields = true }
 // Use this to clean wrongly formatted values.
 func (d *Decoder) SetMapFunc(fn MapFunc) { d.mapFunc = fn }
 
+// SetBoolFormat causes the Decoder to decode bool fields using f.
+// Fields with a bool tag option are not affected.
+// The default is to use strconv.ParseBool.
+func (d *Decoder) SetBoolFormat(f BoolFormat) { d.boolFormat = &f }
+
+// SetArenaSize causes the Decoder to copy decoded strings into
+// shared blocks of n bytes instead of referencing the records returned by the Reader.
+// This reduces allocations when the Reader reuses its buffers
+// and prevents retained strings from keeping entire records alive.
+// Strings longer than n are allocated individually.
+// The default is zero, which disables the arena.
+func (d *Decoder) SetArenaSize(n int) { d.arenaSize = n }
+
+// SetConcurrency causes the Decoder to read records on one goroutine
+// and convert them to structs on n worker goroutines.
+// Rows are still delivered in input order and the error of the first
+// failing row is reported. The MapFunc and the methods of the
+// row type must be safe for concurrent use.
+// The default is 1, which decodes on the calling goroutine.
+func (d *Decoder) SetConcurrency(n int) { d.concurrency = n }
+
+// SetCapacityHint causes Decode and DecodeAppend to reserve room
+// for n rows before decoding to avoid growing the slice.
+// A negative n is treated as 0.
+func (d *Decoder) SetCapacityHint(n int) {
+	if n < 0 {
+		n = 0
+	}
+	d.capacityHint = n
+}
+
+// SetObserver causes the Decoder to report its progress to o
+// after every interval rows and when decoding ends.
+// If interval is zero or negative, progress is only reported at the end.
+func (d *Decoder) SetObserver(o Observer, interval int) {
+	d.observer = o
+	d.observeInterval = interval
+}
+
 // SkipHeader causes the Decoder to not parse the first
 // record as the header but to derive it from the struct tags.
 // Use this to read headerless CSVs.
 func (d *Decoder) SkipHeader() { d.skipHeader = true }
 
 // SetReaderFunc customizes how records are decoded.
-// The default value is NewReader.
-func (d *Decoder) SetReaderFunc(fn ReaderFunc) { d.readerFunc = fn }
+// The default value is the NewReader method of a Dialect with comma as the delimiter.
+// Use NewReader to decode records using csv.Reader.
+func (d *Decoder) SetReaderFunc(fn ReaderFunc) {
+	d.readerFunc = fn
+	d.customReader = true
+	d.readerFuncSet = true
+}
+
+// SetLimits causes the Decoder to return a LimitError
+// when the input exceeds any of the limits.
+// Custom Readers set by SetReaderFunc are checked after each record is read.
+func (d *Decoder) SetLimits(l Limits) { d.limits = l }
 
 // DecoderIterator decodes one row at a time
 // to enable parsing of large files without
@@ -99,7 +207,12 @@ func (d *Decoder) SetReaderFunc(fn ReaderFunc) { d.readerFunc = fn }
 type DecoderIterator struct {
 	state decodeState
 	vv    reflect.Value
+	row   reflect.Value
 	err   error
+	pipe  *pipeline
+	batch *batch
+	index int
+	end   recordEnd
 }
 
 // Err returns the most recent non-EOF error.
@@ -112,22 +225,61 @@ func (d *DecoderIterator) Err() error {
 // After Scan returns false, Err will return the error that caused it to stop.
 // If Scan stopped because it has reached EOF, Err will return nil.
 func (d *DecoderIterator) Scan() bool {
-	var vn reflect.Value
-	vn, d.err = d.state.next()
+	if d.pipe != nil {
+		return d.scanPipeline()
+	}
+
+	d.err = d.state.decode(d.row)
 	if errors.Is(d.err, io.EOF) {
 		d.err = nil
+		d.Close()
 		return false
 	} else if d.err != nil {
 		return false
 	}
 
-	d.vv.Elem().Set(vn.Elem()) // *vv = *vn
+	d.vv.Elem().Set(d.row) // *vv = row
+	d.end = d.state.end
+	return true
+}
+
+func (d *DecoderIterator) scanPipeline() bool {
+	for d.batch == nil || d.index >= d.batch.n {
+		if d.batch != nil && d.batch.err != nil {
+			if d.err = d.batch.err; d.err == io.EOF {
+				d.err = nil
+			}
+			d.Close()
+			return false
+		}
+		d.batch, d.index = d.pipe.nextBatch(), 0
+	}
+
+	d.vv.Elem().Set(d.batch.rows.Index(d.index)) // *vv = rows[index]
+	d.end = d.batch.pos[d.index].end
+	d.index++
 	return true
 }
 
+// Close releases the goroutines started by a Decoder with concurrency enabled
+// and reports the end of decoding to the Observer.
+// It needs to be called only if the iterator is abandoned before Scan returns false.
+func (d *DecoderIterator) Close() {
+	if d.pipe != nil {
+		d.pipe.close()
+	}
+	d.state.obs.done()
+}
+
 // Iterate returns a DecoderIterator that decodes each row into v,
 // which must be a pointer to a struct.
 func (d *Decoder) Iterate(v interface{}) (*DecoderIterator, error) {
+	return d.IterateContext(context.Background(), v)
+}
+
+// IterateContext is like Iterate but Scan stops when ctx is done.
+// Err then returns an error that wraps ctx.Err() and reports the line reached.
+func (d *Decoder) IterateContext(ctx context.Context, v interface{}) (*DecoderIterator, error) {
 	var vv reflect.Value
 	vv, err := valueOf(v)
 	if err != nil {
@@ -145,99 +297,327 @@ func (d *Decoder) Iterate(v interface{}) (*DecoderIterator, error) {
 		return nil, err
 	}
 
+	iter.state.done = ctx.Done()
+	iter.state.ctx = ctx
+	iter.state.track = true
+	iter.end = iter.state.end
+
 	iter.vv = vv
+	iter.row = reflect.New(structType).Elem()
+
+	if d.concurrency > 1 {
+		iter.pipe = newPipeline(&iter.state, d.concurrency)
+	}
+
 	return &iter, nil
 }
 
 type decodeState struct {
 	*Decoder
-	r          Reader
-	structType reflect.Type
-	fields     []structField
-	indices    []int
+	r           Reader
+	structType  reflect.Type
+	header      []string
+	fields      []compiledField
+	nfields     int
+	zero        reflect.Value
+	arena       *arena
+	unmarshaler bool
+	validate    bool
+	ctx         context.Context
+	done        <-chan struct{} // nil if ctx cannot be canceled
+	rows        *int64          // number of rows read
+	end         recordEnd       // position following the last record read
+	track       bool            // update end after every record
+	obs         *observation    // nil if there is no observer
 }
 
 func (s *decodeState) init(d *Decoder, structType reflect.Type) error {
-	r := d.readerFunc(d.reader)
+	s.obs = newObservation(d.observer, d.observeInterval)
+
+	if d.checkpoint != nil {
+		return s.resume(d, structType)
+	}
+
+	// the byte order mark is skipped for all Readers
+	bom := &bomReader{r: d.charset.NewDecoder(s.obs.reader(d.reader))}
 
-	header, err := getHeader(structType, r, d.skipHeader)
+	src, skipped, err := d.readPreamble(bom)
 	if err != nil {
 		return err
 	}
 
-	fields, indices, err := headerFieldsIndices(structType, header)
+	if d.autoDetect {
+		// sniff a buffered prefix so that it can still be read
+		br := bufio.NewReaderSize(src, sniffSize)
+		if d, err = d.detectDialect(br); err != nil {
+			return err
+		}
+		src = br
+	}
+
+	var r Reader = &bomOffsetReader{d.newReader(src), bom}
+	if skipped > 0 {
+		r = &lineOffsetReader{Reader: r, offset: len(d.preamble), inputOffset: skipped}
+	}
+
+	header, err := d.getHeader(structType, r)
 	if err != nil {
 		return err
 	}
 
+	end := recordEnd{next: len(d.preamble) + 1, offset: skipped + bom.skipped}
+	if !d.skipHeader {
+		end = endOf(r, header)
+	}
+
+	if err := s.setup(d, r, structType, header); err != nil {
+		return err
+	}
+
+	s.end = end
+	return nil
+}
+
+// setup prepares the state to decode the records of r, given the header.
+func (s *decodeState) setup(d *Decoder, r Reader, structType reflect.Type, header []string) error {
 	s.Decoder = d
 	s.r = r
 	s.structType = structType
-	s.fields = fields
-	s.indices = indices
+	s.header = append([]string{}, header...) // the Reader may reuse the header slice
+	s.zero = reflect.Zero(structType)
+	s.rows = new(int64)
+	s.unmarshaler = reflect.PtrTo(structType).Implements(recordUnmarshalerType)
+	s.validate = reflect.PtrTo(structType).Implements(validatorType)
+
+	if d.arenaSize > 0 {
+		s.arena = &arena{size: d.arenaSize}
+	}
+
+	if s.unmarshaler {
+		s.nfields = len(header)
+		if counter, ok := reflect.New(structType).Interface().(CSVColumnCounter); ok {
+			s.nfields = counter.CSVColumnCount()
+			return checkGenerated(structType, d.boolFormat)
+		}
+		return nil
+	}
+
+	fields, indices, err := headerFieldsIndices(structType, header)
+	if err != nil {
+		return err
+	}
+
+	if d.boolFormat != nil {
+		fields = withBoolFormat(fields, d.boolFormat)
+	}
+
+	s.fields = compileFields(structType, fields, indices)
+	s.nfields = len(fields)
 	return nil
 }
 
-func (s *decodeState) next() (reflect.Value, error) {
-	record, err := s.r.Read()
+// lineError wraps an error that applies to the record at pos.
+func lineError(pos interface{}, err error) error {
+	line, _ := fieldPos(pos, 0)
+	return rowError(line, err)
+}
+
+// decodeAll decodes all rows and appends them to slice.
+func (s *decodeState) decodeAll(slice reflect.Value) (reflect.Value, error) {
+	if slice.Type().Elem().Kind() == reflect.Ptr {
+		return s.decodeAllPtr(slice)
+	}
+
+	// rows are decoded directly into the backing array
+	sv := reflect.New(slice.Type()).Elem()
+	sv.Set(slice)
+
+	for {
+		n := sv.Len()
+		if n == sv.Cap() {
+			grown := reflect.MakeSlice(sv.Type(), n, 2*n+8)
+			reflect.Copy(grown, sv)
+			sv.Set(grown)
+		}
+
+		sv.SetLen(n + 1)
+		if err := s.decode(sv.Index(n)); err == io.EOF {
+			sv.SetLen(n)
+			return sv, nil
+		} else if err != nil {
+			return sv, err
+		}
+	}
+}
+
+// decodeAllPtr decodes all rows and appends pointers to them to slice.
+// The rows are allocated in blocks to amortize allocations.
+func (s *decodeState) decodeAllPtr(slice reflect.Value) (reflect.Value, error) {
+	const blockSize = 64
+
+	blockType := reflect.SliceOf(s.structType)
+	block := reflect.MakeSlice(blockType, blockSize, blockSize)
+
+	for i := 0; ; i++ {
+		if i == blockSize {
+			block, i = reflect.MakeSlice(blockType, blockSize, blockSize), 0
+		}
+
+		row := block.Index(i)
+		if err := s.decode(row); err == io.EOF {
+			return slice, nil
+		} else if err != nil {
+			return slice, err
+		}
 
+		slice = reflect.Append(slice, row.Addr())
+	}
+}
+
+// read reads the next record.
+func (s *decodeState) read() ([]string, error) {
+	record, err := s.r.Read()
 	if err == io.EOF {
-		return reflect.Value{}, err
+		return nil, err
 	} else if err != nil {
-		return reflect.Value{}, fmt.Errorf("csv: %w", err)
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	if s.done != nil {
+		select {
+		case <-s.done:
+			return nil, lineError(s.r, s.ctx.Err())
+		default:
+		}
 	}
 
+	if s.limits.MaxRows > 0 {
+		if err := s.countRow(); err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+	}
+
+	if s.track {
+		s.end = endOf(s.r, record)
+	}
+
+	return record, nil
+}
+
+// decode reads the next record and decodes it into row,
+// which must be an addressable struct value.
+func (s *decodeState) decode(row reflect.Value) error {
+	record, err := s.read()
+	if err != nil {
+		return err
+	}
+	err = s.convert(row, record, s.r)
+	s.obs.row(err != nil)
+	return err
+}
+
+// convert decodes record into row. The positions reported
+// in errors are obtained from pos if it implements FieldPos.
+func (s *decodeState) convert(row reflect.Value, record []string, pos interface{}) (err error) {
 	// disallow records that have more or fewer columns than struct fields
-	if (s.disallowShortFields && len(record) < len(s.fields)) || (s.disallowUnknownFields && len(record) > len(s.fields)) {
-		line, _ := fieldPos(s.r, 0)
-		return reflect.Value{}, fmt.Errorf("csv: %w", &csv.ParseError{
-			StartLine: line,
-			Line:      line,
-			Column:    1,
-			Err:       csv.ErrFieldCount,
-		})
+	if (s.disallowShortFields && len(record) < s.nfields) || (s.disallowUnknownFields && len(record) > s.nfields) {
+		return lineError(pos, csv.ErrFieldCount)
 	}
 
-	structval := reflect.New(s.structType) // new(T)
+	row.Set(s.zero) // row = T{}
 
-	// loop through every (column index, struct field index) pair
-	for i := 0; i < len(s.indices); i += 2 {
-		var value string
-		var fieldidx int
+	if s.unmarshaler {
+		// clean the record and let the row decode itself
+		for i := 0; i < len(record) && i < len(s.header); i++ {
+			record[i] = s.mapFunc(s.header[i], record[i])
+		}
+		if err = row.Addr().Interface().(CSVRecordUnmarshaler).UnmarshalCSVRecord(s.header, record); err != nil {
+			var cerr *ColumnError
+			if errors.As(err, &cerr) && cerr.Column >= 0 && cerr.Column < len(record) {
+				return fieldError(pos, cerr.Column, cerr.Err)
+			}
+			return lineError(pos, err)
+		}
+	}
+
+	base := unsafe.Pointer(row.UnsafeAddr())
+
+	for i := range s.fields {
+		field := &s.fields[i]
 		// get column if it is within range
-		if fieldidx = s.indices[i]; fieldidx < len(record) {
-			value = record[fieldidx]
+		var value string
+		if field.Column < len(record) {
+			value = record[field.Column]
 		}
-		// get the struct field
-		field := s.fields[s.indices[i+1]]
-		fieldval := structval.Elem().FieldByIndex(field.Index)
-		// clean the value string and type convert it
+		// clean the value string, type convert and validate it
 		value = s.mapFunc(field.Name, value)
-		if err = field.Decode(fieldval, value); err != nil {
-			if fieldidx >= len(record) {
-				fieldidx = 0
+		if field.str && s.arena != nil {
+			value = s.arena.string(value)
+		}
+		if field.set != nil {
+			err = field.set(unsafe.Add(base, field.Offset), value)
+		} else {
+			err = field.Decode(field.value(base), value)
+		}
+		if err == nil && field.Constraints != nil {
+			err = field.validate(field.value(base), value)
+		}
+		if err != nil {
+			column := field.Column
+			if column >= len(record) {
+				column = 0
 			}
-			line, column := fieldPos(s.r, fieldidx)
-			return reflect.Value{}, fmt.Errorf("csv: %w", &csv.ParseError{
-				StartLine: line,
-				Line:      line,
-				Column:    column,
-				Err:       err,
-			})
+			return fieldError(pos, column, err)
+		}
+	}
+
+	// validate the row as a whole
+	if s.validate {
+		if err = row.Addr().Interface().(Validator).CSVValidate(); err != nil {
+			return lineError(pos, err)
 		}
 	}
 
-	return structval, nil
+	return nil
+}
+
+// fieldError wraps an error that applies to the given column of the record at pos.
+func fieldError(pos interface{}, column int, err error) error {
+	line, column := fieldPos(pos, column)
+	return fmt.Errorf("csv: %w", &csv.ParseError{
+		StartLine: line,
+		Line:      line,
+		Column:    column,
+		Err:       err,
+	})
+}
+
+// rowError wraps an error that applies to the record at the given line.
+func rowError(line int, err error) error {
+	return fmt.Errorf("csv: %w", &csv.ParseError{
+		StartLine: line,
+		Line:      line,
+		Column:    1,
+		Err:       err,
+	})
 }
 
-func getHeader(structType reflect.Type, r Reader, skipHeader bool) ([]string, error) {
-	if skipHeader {
-		return headerOf(structType)
+func (d *Decoder) getHeader(structType reflect.Type, r Reader) ([]string, error) {
+	if d.skipHeader {
+		return d.structHeader(structType)
 	}
 
 	return r.Read()
 }
 
+// structHeader returns the header of input streams that have none.
+func (d *Decoder) structHeader(structType reflect.Type) ([]string, error) {
+	if len(d.header) > 0 {
+		return d.header, nil
+	}
+	return headerOf(structType)
+}
+
 func headerFieldsIndices(structType reflect.Type, header []string) (fields []structField, indices []int, err error) {
 	if fields, err = structFieldsOf(structType); err != nil {
 		return
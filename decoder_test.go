@@ -1,6 +1,7 @@
 package csvbuddy
 
 import (
+	"context"
 	"encoding"
 	"encoding/csv"
 	"errors"
@@ -235,3 +236,160 @@ func TestDecoderMapfunc(t *testing.T) {
 		t.Fatal("should be zero and NaN")
 	}
 }
+
+func TestDecodeBoolFormat(t *testing.T) {
+	type struc struct {
+		A bool
+		B *bool
+		C bool `csv:"C,bool=x|"`
+	}
+
+	testdata := "A,B,C\nja,nee,x\nNee,,"
+
+	var data []struc
+
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SetBoolFormat(BoolFormat{
+		True:  []string{"ja"},
+		False: []string{"nee"},
+	})
+
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 2 || !data[0].A || data[0].B == nil || *data[0].B || !data[0].C {
+		t.Fatal("first row not equal")
+	} else if data[1].A || data[1].B != nil || data[1].C {
+		t.Fatal("second row not equal")
+	}
+}
+
+type testPeriod struct {
+	Start int `csv:"start"`
+	End   int `csv:"end"`
+}
+
+var errEndBeforeStart = errors.New("end before start")
+
+func (p *testPeriod) CSVValidate() error {
+	if p.End < p.Start {
+		return errEndBeforeStart
+	}
+	return nil
+}
+
+func (p *testPeriod) BeforeCSVEncode() error {
+	return p.CSVValidate()
+}
+
+func TestDecodeValidator(t *testing.T) {
+	var data []testPeriod
+	if err := Unmarshal([]byte("start,end\n1,2\n3,3"), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	var pe *csv.ParseError
+	if err := Unmarshal([]byte("start,end\n1,2\n3,2"), &data); !errors.Is(err, errEndBeforeStart) {
+		t.Fatal("expected validation error", err)
+	} else if !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatal("expected line 3", err)
+	}
+}
+
+func TestDecodePointers(t *testing.T) {
+	testdata := parallelData(200)
+
+	var expect []parallelRow
+	if err := Unmarshal([]byte(testdata), &expect); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 3} {
+		var data []*parallelRow
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(n)
+		if err := d.Decode(&data); err != nil {
+			t.Fatal(err)
+		} else if len(data) != len(expect) {
+			t.Fatal("wrong length", len(data))
+		}
+		for i := range data {
+			if *data[i] != expect[i] {
+				t.Fatal("not equal", i, n)
+			}
+		}
+	}
+}
+
+func TestDecodeAppend(t *testing.T) {
+	data := []testPeriod{{0, 1}}
+
+	d := NewDecoder(strings.NewReader("start,end\n1,2\n3,4\n"))
+	d.SetCapacityHint(10)
+	if err := d.DecodeAppend(&data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{0, 1}, {1, 2}, {3, 4}}) {
+		t.Fatal("not equal", data)
+	} else if cap(data) < 11 {
+		t.Fatal("capacity hint not honored", cap(data))
+	}
+
+	if err := NewDecoder(strings.NewReader("start,end\n5,6\n8,7\n")).DecodeAppend(&data); !errors.Is(err, errEndBeforeStart) {
+		t.Fatal("expected validation error", err)
+	} else if len(data) != 3 {
+		t.Fatal("slice length should not change on error", data)
+	}
+
+	// a negative capacity hint is ignored
+	d = NewDecoder(strings.NewReader("start,end\n5,6\n"))
+	d.SetCapacityHint(-1)
+	if err := d.DecodeAppend(&data); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 4 {
+		t.Fatal("not appended", data)
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := NewDecoder(strings.NewReader(parallelData(1000)))
+	d.SetMapFunc(func(name, value string) string {
+		if name == "i" && value == "100" {
+			cancel()
+		}
+		return value
+	})
+
+	var data []parallelRow
+	var pe *csv.ParseError
+	if err := d.DecodeContext(ctx, &data); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled", err)
+	} else if !errors.As(err, &pe) || pe.Line != 103 {
+		t.Fatal("expected line 103", err)
+	} else if data != nil {
+		t.Fatal("should not be assigned on error")
+	}
+}
+
+func TestIterateContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, n := range []int{0, 2} {
+		var row parallelRow
+		d := NewDecoder(strings.NewReader(parallelData(1000)))
+		d.SetConcurrency(n)
+
+		iter, err := d.IterateContext(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		} else if iter.Scan() {
+			t.Fatal("expected Scan to stop")
+		} else if err := iter.Err(); !errors.Is(err, context.Canceled) {
+			t.Fatal("expected context.Canceled", err)
+		}
+	}
+}
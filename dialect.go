@@ -0,0 +1,177 @@
+package csvbuddy
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// bom is the UTF-8 byte order mark.
+const bom = "\xef\xbb\xbf"
+
+// Dialect describes the format of a CSV file.
+type Dialect struct {
+	// Comma is the field delimiter.
+	Comma rune
+
+	// Comment, if not 0, is the character that starts a comment line.
+	// Comment lines are skipped when decoding.
+	Comment rune
+
+	// UseCRLF terminates lines with \r\n instead of \n when encoding.
+	// Both are accepted when decoding.
+	UseCRLF bool
+
+	// TrimLeadingSpace ignores leading white space in a field when decoding.
+	TrimLeadingSpace bool
+
+	// LazyQuotes allows quotes to appear in unquoted fields
+	// and non-doubled quotes to appear in quoted fields when decoding.
+	LazyQuotes bool
+
+	// BOM writes a UTF-8 byte order mark when encoding.
+	// A byte order mark is always skipped when decoding.
+	BOM bool
+
+	// Quoting is the policy that decides which fields are quoted when encoding.
+	Quoting Quoting
+
+	// Escape is the character that precedes special characters when encoding
+	// with QuoteNone. Escaped fields cannot be decoded by csv.Reader.
+	Escape rune
+}
+
+// Dialect presets.
+var (
+	// DialectRFC4180 is the format described in RFC 4180.
+	DialectRFC4180 = Dialect{Comma: ',', UseCRLF: true}
+
+	// DialectExcel is the format written by Microsoft Excel.
+	DialectExcel = Dialect{Comma: ',', UseCRLF: true, BOM: true}
+
+	// DialectExcelEU is the format written by Microsoft Excel
+	// in locales that use the comma as decimal separator.
+	DialectExcelEU = Dialect{Comma: ';', UseCRLF: true, BOM: true}
+
+	// DialectTSV is the tab-separated values format.
+	DialectTSV = Dialect{Comma: '\t', LazyQuotes: true}
+)
+
+// NewReader returns a FastReader that reads from r using the dialect.
+// A UTF-8 byte order mark at the start of r is skipped.
+func (d Dialect) NewReader(r io.Reader) Reader {
+	br := &bomReader{r: r}
+
+	fr := NewFastReader(br)
+	fr.Comma = d.Comma
+	fr.Comment = d.Comment
+	fr.TrimLeadingSpace = d.TrimLeadingSpace
+	fr.LazyQuotes = d.LazyQuotes
+
+	return &bomOffsetReader{fr, br}
+}
+
+// NewWriter returns a Writer that writes to w using the dialect.
+func (d Dialect) NewWriter(w io.Writer) Writer {
+	if d.BOM {
+		w = &bomWriter{w: w}
+	}
+
+	if d.Quoting != QuoteMinimal {
+		return newQuotingWriter(w, d)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = d.Comma
+	cw.UseCRLF = d.UseCRLF
+	return cw
+}
+
+// SetDialect causes the Decoder to read records using the dialect.
+// It replaces the ReaderFunc.
+func (d *Decoder) SetDialect(dialect Dialect) {
+	d.readerFunc = dialect.NewReader
+	d.dialect = dialect
+	d.readerFuncSet = false
+	// record boundaries cannot be found by counting quotes if quotes may be bare
+	d.customReader = dialect.LazyQuotes
+}
+
+// SetDialect causes the Encoder to write records using the dialect.
+// It replaces the WriterFunc.
+func (e *Encoder) SetDialect(dialect Dialect) {
+	e.writerFunc = dialect.NewWriter
+	e.crlf = dialect.UseCRLF
+}
+
+// bomOffsetReader adds the size of a skipped byte order mark to the input offset.
+type bomOffsetReader struct {
+	Reader
+	bom *bomReader
+}
+
+func (r *bomOffsetReader) InputOffset() int64 {
+	if offset := inputOffset(r.Reader); offset >= 0 {
+		return offset + r.bom.skipped
+	}
+	return -1
+}
+
+func (r *bomOffsetReader) FieldPos(field int) (line, column int) {
+	return fieldPos(r.Reader, field)
+}
+
+// bomReader skips the byte order mark at the start of r.
+type bomReader struct {
+	r       io.Reader
+	pending []byte
+	checked bool
+	skipped int64
+}
+
+// check reads the start of r and skips the byte order mark if present.
+func (b *bomReader) check() error {
+	if b.checked {
+		return nil
+	}
+
+	b.checked = true
+	buf := make([]byte, len(bom))
+	n, err := io.ReadFull(b.r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	} else if string(buf[:n]) == bom {
+		b.skipped, n = int64(n), 0
+	}
+	b.pending = buf[:n]
+	return nil
+}
+
+func (b *bomReader) Read(p []byte) (int, error) {
+	if err := b.check(); err != nil {
+		return 0, err
+	}
+
+	if len(b.pending) > 0 {
+		n := copy(p, b.pending)
+		b.pending = b.pending[n:]
+		return n, nil
+	}
+
+	return b.r.Read(p)
+}
+
+// bomWriter writes the byte order mark before the first write to w.
+type bomWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (b *bomWriter) Write(p []byte) (int, error) {
+	if !b.written {
+		b.written = true
+		if _, err := io.WriteString(b.w, bom); err != nil {
+			return 0, err
+		}
+	}
+	return b.w.Write(p)
+}
@@ -0,0 +1,65 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	data := []testPeriod{{1, 2}, {3, 4}}
+
+	for _, test := range []struct {
+		Dialect Dialect
+		Text    string
+	}{
+		{DialectRFC4180, "start,end\r\n1,2\r\n3,4\r\n"},
+		{DialectExcel, bom + "start,end\r\n1,2\r\n3,4\r\n"},
+		{DialectExcelEU, bom + "start;end\r\n1;2\r\n3;4\r\n"},
+		{DialectTSV, "start\tend\n1\t2\n3\t4\n"},
+	} {
+		var b bytes.Buffer
+		e := NewEncoder(&b)
+		e.SetDialect(test.Dialect)
+		if err := e.Encode(&data); err != nil {
+			t.Fatal(err)
+		} else if b.String() != test.Text {
+			t.Fatalf("%q", b.String())
+		}
+
+		var data2 []testPeriod
+		d := NewDecoder(&b)
+		d.SetDialect(test.Dialect)
+		if err := d.Decode(&data2); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, data2) {
+			t.Fatal("not equal", data2)
+		}
+	}
+}
+
+func TestDialectReader(t *testing.T) {
+	dialect := Dialect{
+		Comma:            ';',
+		Comment:          '#',
+		TrimLeadingSpace: true,
+		BOM:              true,
+	}
+
+	var data []testPeriod
+	d := NewDecoder(strings.NewReader("# periods\nstart; end\n1; 2\n"))
+	d.SetDialect(dialect)
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}}) {
+		t.Fatal("not equal", data)
+	}
+
+	r := dialect.NewReader(strings.NewReader(bom + "a;b\n"))
+	if record, err := r.Read(); err != nil || strings.Join(record, ",") != "a,b" {
+		t.Fatal(record, err)
+	} else if offset := inputOffset(r); offset != 7 {
+		t.Fatal("input offset should include the byte order mark", offset)
+	}
+}
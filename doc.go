@@ -1,6 +1,7 @@
 // Package csvbuddy implements a convenient interface for encoding and decoding CSV files.
 //
 // Only slices of structs can be encoded and decoded because CSV is defined as a list of records.
+// Large outputs can be streamed one struct at a time using Encoder.EncodeRow or RowWriter.
 //
 // Every exported struct field is interpreted as a CSV column.
 // Struct fields are automatically mapped by name to a CSV column.
@@ -17,6 +18,16 @@
 //      Hex uint `csv:"addr,base=16"`
 //      // Use prec and fmt to set floating point precision and format. Default is -1 and 'f'.
 //      Flt float64 `csv:"flt,prec=6,fmt=E"`
+//      // Use bool to set the true and false strings separated by a pipe.
+//      // Default is strconv.ParseBool and strconv.FormatBool.
+//      Flag bool `csv:"flag,bool=Y|N"`
+//      // Use oneof to restrict the values of a field, separated by a pipe.
+//      Status string `csv:"status,oneof=active|suspended|closed"`
+//      // Use min and max to bound numeric values when decoding.
+//      Age int `csv:"age,min=0,max=150"`
+//      // Use minlen, maxlen and pattern to validate the text when decoding.
+//      // The pattern must be the last option because it may contain commas.
+//      Code string `csv:"code,minlen=2,maxlen=3,pattern=^[A-Z]+$"`
 //      // Inline structs with inline tag.
 //      // Any csv fields in the inlined struct are also (un)marshaled.
 //      // Beware of naming clashes.
@@ -27,9 +38,13 @@
 //
 // The following struct field types are supported:
 // bool, int[8, 16, 32, 64], uint[8, 16, 32, 64], float[32, 64], complex[64, 128],
-// []byte, string, encoding.TextMarshaler, encoding.TextUnmarshaler.
+// []byte, string, encoding.TextMarshaler, encoding.TextUnmarshaler,
+// CSVFieldMarshaler, CSVFieldUnmarshaler.
 // Other values produce an error.
 //
+// Integer types can be encoded and decoded by name by registering
+// a name table with RegisterEnum.
+//
 // Pointers to any of the above types are interpreted as optional types.
 // Optional types are decoded if the parsed field is not an empty string,
 // and they are encoded as an empty string if the pointer is nil.
@@ -2,17 +2,26 @@ package csvbuddy
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"reflect"
 )
 
 // Encoder writes and encodes CSV records to an output stream.
 type Encoder struct {
-	writer     io.Writer
-	writerFunc WriterFunc
-	mapFunc    MapFunc
-	header     []string
-	skipHeader bool
+	writer          io.Writer
+	writerFunc      WriterFunc
+	mapFunc         MapFunc
+	boolFormat      *BoolFormat
+	header          []string
+	skipHeader      bool
+	row             *encodeState // state of EncodeRow
+	observer        Observer
+	observeInterval int
+	charset         Charset
+	escapeFormulas  bool
+	preamble        []string
+	crlf            bool // the dialect uses CRLF line endings
 }
 
 // NewEncoder creates a new Encoder.
@@ -24,81 +33,108 @@ func NewEncoder(w io.Writer) *Encoder {
 	}
 }
 
-// Encode encodes a slice of structs to CSV text format.
-// The value of v must be a pointer to a slice of structs.
-func (e *Encoder) Encode(v interface{}) (err error) {
+// Encode encodes a sequence of structs to CSV text format.
+// The value of v must be a pointer to a slice of T,
+// a func(yield func(T) bool) such as iter.Seq[T],
+// a func(yield func(T, error) bool) such as iter.Seq2[T, error],
+// or a receive channel of T, where T is a struct or a pointer to a struct.
+// Encoding stops at the first error, including an error yielded by the iterator.
+// A channel is not drained when encoding stops early.
+func (e *Encoder) Encode(v interface{}) error {
+	return e.EncodeContext(context.Background(), v)
+}
+
+// EncodeContext is like Encode but stops when ctx is done.
+// The returned error wraps ctx.Err() and reports the line reached.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}) (err error) {
 	var vv reflect.Value
 	if vv, err = valueOf(v); err != nil {
 		return
 	}
 
-	structType := innerTypeOf(vv.Type(), reflect.Ptr, reflect.Slice, reflect.Struct)
-	if structType == nil {
-		return ErrInvalidArgument
+	src, err := sourceOf(vv)
+	if err != nil {
+		return
 	}
 
-	var header []string
-	if len(e.header) > 0 {
-		header = e.header
-	} else if header, err = headerOf(structType); err != nil {
+	var state encodeState
+	err = state.init(e, src.structType)
+	defer state.obs.done()
+	if err != nil {
 		return
 	}
 
-	var fields []structField
-	var indices []int
-	if fields, err = structFieldsOf(structType); err != nil {
-		return
-	} else if indices, err = headerIndices(header, fields); err != nil {
+	state.done = ctx.Done()
+	state.ctx = ctx
+
+	if err = src.each(state.encode); err != nil {
 		return
 	}
 
-	w := e.writerFunc(e.writer)
+	return state.flush()
+}
 
-	if !e.skipHeader {
-		if err = w.Write(header); err != nil {
-			return
-		}
+// EncodeRow encodes a single struct to CSV text format.
+// The value of v must be a pointer to a struct.
+// The header is written before the first row and every row
+// must have the same type as the first.
+// Rows may be buffered until Flush or Close is called.
+func (e *Encoder) EncodeRow(v interface{}) (err error) {
+	var vv reflect.Value
+	if vv, err = valueOf(v); err != nil {
+		return
 	}
 
-	record := make([]string, len(header))
-	slice := vv.Elem()
-	for i := 0; i < slice.Len(); i++ {
-		structval := slice.Index(i)
-		for j := 0; j < len(indices); j += 2 {
-			field := fields[indices[j+1]]
-			fieldval := structval.FieldByIndex(field.Index)
-			var value string
-			if value, err = field.Encode(fieldval); err != nil {
-				return
-			}
-			record[indices[j]] = e.mapFunc(field.Name, value)
-		}
-
-		if err = w.Write(record); err != nil {
-			return
-		}
+	structType := innerTypeOf(vv.Type(), reflect.Ptr, reflect.Struct)
+	if structType == nil {
+		return ErrInvalidArgument
 	}
 
-	type csvFlusher interface {
-		Flush()
-		Error() error
+	if err = e.initRow(structType); err != nil {
+		return
 	}
 
-	type flusher interface {
-		Flush() error
+	return e.row.encode(vv.Elem())
+}
+
+// initRow prepares the Encoder to encode rows of structType.
+func (e *Encoder) initRow(structType reflect.Type) error {
+	if e.row == nil {
+		var state encodeState
+		if err := state.init(e, structType); err != nil {
+			return err
+		}
+		e.row = &state
+	} else if e.row.structType != structType {
+		return ErrInvalidArgument
 	}
+	return nil
+}
 
-	// special case for csv.Writer because Flush does not return an error
-	if csvw, ok := w.(csvFlusher); ok {
-		csvw.Flush()
-		return csvw.Error()
-	} else if flusher, ok := w.(flusher); ok {
-		return flusher.Flush()
+// Flush writes any rows buffered by EncodeRow to the output stream.
+func (e *Encoder) Flush() error {
+	if e.row == nil {
+		return nil
 	}
+	return e.row.flush()
+}
 
-	return nil
+// Close flushes the rows written by EncodeRow and resets the Encoder,
+// so that the next call to EncodeRow writes a new header.
+func (e *Encoder) Close() error {
+	err := e.Flush()
+	if e.row != nil {
+		e.row.obs.done()
+	}
+	e.row = nil
+	return err
 }
 
+// SetBoolFormat causes the Encoder to encode bool fields using f.
+// Fields with a bool tag option are not affected.
+// The default is to use strconv.FormatBool.
+func (e *Encoder) SetBoolFormat(f BoolFormat) { e.boolFormat = &f }
+
 // SetHeader causes the Encoder to change the order in which fields are encoded.
 func (e *Encoder) SetHeader(h []string) { e.header = h }
 
@@ -107,7 +143,19 @@ func (e *Encoder) SetMapFunc(fn MapFunc) { e.mapFunc = fn }
 
 // SetWriterFunc customizes how records are encoded.
 // The default value is NewWriter.
-func (e *Encoder) SetWriterFunc(fn WriterFunc) { e.writerFunc = fn }
+func (e *Encoder) SetWriterFunc(fn WriterFunc) {
+	e.writerFunc = fn
+	e.crlf = false
+}
+
+// SetObserver causes the Encoder to report its progress to o
+// after every interval rows and when encoding ends.
+// If interval is zero or negative, progress is only reported at the end.
+// Rows encoded by EncodeRow are reported until Close is called.
+func (e *Encoder) SetObserver(o Observer, interval int) {
+	e.observer = o
+	e.observeInterval = interval
+}
 
 // SkipHeader causes the Encoder to not write the CSV header.
 func (e *Encoder) SkipHeader() { e.skipHeader = true }
@@ -121,3 +169,164 @@ func Marshal(v interface{}) ([]byte, error) {
 	}
 	return b.Bytes(), nil
 }
+
+type encodeState struct {
+	*Encoder
+	w            Writer
+	structType   reflect.Type
+	header       []string
+	fields       []structField
+	indices      []int
+	record       []string
+	line         int // line of the next record
+	marshaler    bool
+	beforeEncode bool
+	escapes      []bool          // columns that are escaped by escapeFormula, nil if none
+	preamble     *preambleWriter // nil if there is no preamble
+	obs          *observation    // nil if there is no observer
+	ctx          context.Context
+	done         <-chan struct{} // nil if ctx cannot be canceled
+}
+
+// init prepares the state to encode structs of structType and writes the header.
+func (s *encodeState) init(e *Encoder, structType reflect.Type) (err error) {
+	s.Encoder = e
+	s.structType = structType
+	s.obs = newObservation(e.observer, e.observeInterval)
+
+	if len(e.header) > 0 {
+		s.header = e.header
+	} else if s.header, err = headerOf(structType); err != nil {
+		return
+	}
+
+	s.marshaler = reflect.PtrTo(structType).Implements(recordMarshalerType)
+	s.beforeEncode = reflect.PtrTo(structType).Implements(beforeEncoderType)
+
+	if s.marshaler && reflect.PtrTo(structType).Implements(columnCounterType) {
+		if err = checkGenerated(structType, e.boolFormat); err != nil {
+			return
+		}
+	} else if !s.marshaler {
+		if s.fields, err = structFieldsOf(structType); err != nil {
+			return
+		} else if s.indices, err = headerIndices(s.header, s.fields); err != nil {
+			return
+		}
+	}
+
+	if e.boolFormat != nil {
+		s.fields = withBoolFormat(s.fields, e.boolFormat)
+	}
+
+	if e.escapeFormulas {
+		s.escapes = formulaColumns(structType, s.header)
+	}
+
+	w := e.charset.NewEncoder(s.obs.writer(e.writer))
+	if len(e.preamble) > 0 {
+		newline := "\n"
+		if e.crlf {
+			newline = "\r\n"
+		}
+		s.preamble = &preambleWriter{w: w, lines: e.preamble, newline: newline}
+		w = s.preamble
+	}
+
+	s.w = e.writerFunc(w)
+	s.record = make([]string, len(s.header))
+
+	// line of the first record
+	s.line = 1
+	if !e.skipHeader {
+		if err = s.w.Write(s.header); err != nil {
+			return
+		}
+		s.line++
+	}
+
+	return nil
+}
+
+// encode encodes and writes structval, which must be an addressable struct value.
+func (s *encodeState) encode(structval reflect.Value) (err error) {
+	line := s.line
+	s.line++
+
+	if s.done != nil {
+		select {
+		case <-s.done:
+			return rowError(line, s.ctx.Err())
+		default:
+		}
+	}
+
+	err = s.write(line, structval)
+	s.obs.row(err != nil)
+	return err
+}
+
+// write encodes and writes the row at the given line.
+func (s *encodeState) write(line int, structval reflect.Value) (err error) {
+	record := s.record
+	if s.beforeEncode {
+		if err = structval.Addr().Interface().(BeforeEncoder).BeforeCSVEncode(); err != nil {
+			return rowError(line, err)
+		}
+	}
+	if s.marshaler {
+		if record, err = structval.Addr().Interface().(CSVRecordMarshaler).MarshalCSVRecord(s.header); err != nil {
+			return rowError(line, err)
+		}
+		for j := 0; j < len(record) && j < len(s.header); j++ {
+			record[j] = s.mapFunc(s.header[j], record[j])
+			if s.escapes != nil && s.escapes[j] {
+				record[j] = escapeFormula(record[j])
+			}
+		}
+	}
+	for j := 0; j < len(s.indices); j += 2 {
+		field := s.fields[s.indices[j+1]]
+		fieldval := structval.FieldByIndex(field.Index)
+		var value string
+		if value, err = field.Encode(fieldval); err != nil {
+			return
+		}
+		value = s.mapFunc(field.Name, value)
+		if s.escapes != nil && s.escapes[s.indices[j]] {
+			value = escapeFormula(value)
+		}
+		record[s.indices[j]] = value
+	}
+
+	return s.w.Write(record)
+}
+
+// flush flushes the Writer if it supports flushing.
+func (s *encodeState) flush() error {
+	type csvFlusher interface {
+		Flush()
+		Error() error
+	}
+
+	type flusher interface {
+		Flush() error
+	}
+
+	var err error
+
+	// special case for csv.Writer because Flush does not return an error
+	if csvw, ok := s.w.(csvFlusher); ok {
+		csvw.Flush()
+		err = csvw.Error()
+	} else if flusher, ok := s.w.(flusher); ok {
+		err = flusher.Flush()
+	}
+
+	// the preamble is still pending if nothing was written
+	if err == nil && s.preamble != nil {
+		err = s.preamble.flush()
+	}
+
+	return err
+}
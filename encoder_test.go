@@ -2,6 +2,9 @@ package csvbuddy
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -38,3 +41,117 @@ func TestEncode(t *testing.T) {
 		t.Fatal(b.String())
 	}
 }
+
+func TestEncodeBoolFormat(t *testing.T) {
+	data := []struct {
+		A bool
+		B bool `csv:"B,bool=Y|N"`
+	}{
+		{true, true},
+		{false, false},
+	}
+
+	var b bytes.Buffer
+
+	e := NewEncoder(&b)
+	e.SetBoolFormat(BoolFormat{
+		True:  []string{"yes"},
+		False: []string{"no"},
+	})
+
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "A,B\nyes,Y\nno,N\n" {
+		t.Fatal(b.String())
+	}
+}
+
+func TestEncodeBeforeEncoder(t *testing.T) {
+	data := []testPeriod{{1, 2}, {3, 4}, {5, 4}}
+
+	var b bytes.Buffer
+
+	var pe *csv.ParseError
+	if err := NewEncoder(&b).Encode(&data); !errors.Is(err, errEndBeforeStart) {
+		t.Fatal("expected validation error", err)
+	} else if !errors.As(err, &pe) || pe.Line != 4 {
+		t.Fatal("expected line 4", err)
+	}
+}
+
+func TestEncodeRow(t *testing.T) {
+	type row struct {
+		A string
+		B int
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+
+	for i, s := range []string{"x", "y"} {
+		if err := e.EncodeRow(&row{s, i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.EncodeRow(&testPeriod{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatal("expected type mismatch", err)
+	} else if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "A,B\nx,0\ny,1\n" {
+		t.Fatal(b.String())
+	}
+
+	// a new header is written after Close
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := e.EncodeRow(&testPeriod{1, 0}); !errors.Is(err, errEndBeforeStart) {
+		t.Fatal("expected validation error", err)
+	} else if err := e.Close(); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasSuffix(b.String(), "\nstart,end\n") {
+		t.Fatal(b.String())
+	}
+}
+
+func TestEncodePointers(t *testing.T) {
+	data := []*testPeriod{{1, 2}, {3, 4}}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(&data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "start,end\n1,2\n3,4\n" {
+		t.Fatal(b.String())
+	}
+
+	data = append(data, nil)
+	if err := NewEncoder(&b).Encode(&data); err != ErrInvalidArgument {
+		t.Fatal("expected ErrInvalidArgument", err)
+	}
+}
+
+func TestEncodeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	seq := func(yield func(testPeriod) bool) {
+		for i := 0; i < 10; i++ {
+			if i == 2 {
+				cancel()
+			}
+			if !yield(testPeriod{i, i}) {
+				return
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	var pe *csv.ParseError
+	if err := NewEncoder(&b).EncodeContext(ctx, seq); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled", err)
+	} else if !errors.As(err, &pe) || pe.Line != 4 {
+		t.Fatal("expected line 4", err)
+	}
+}
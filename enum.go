@@ -0,0 +1,160 @@
+package csvbuddy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrNotOneOf signals that a value is not one of the allowed values of a field.
+var ErrNotOneOf = errors.New("value is not one of the allowed values")
+
+var enumCache = map[reflect.Type]*enumTable{}
+
+// FieldError reports a field value that violates
+// a constraint declared in its struct field tag.
+type FieldError struct {
+	Name    string   // column name
+	Value   string   // offending value
+	Allowed []string // allowed values, if the constraint is an enumeration
+	Err     error    // violated constraint
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Allowed) > 0 {
+		return fmt.Sprintf("field '%s': '%s' is not one of [%s]", e.Name, e.Value, strings.Join(e.Allowed, ", "))
+	}
+	return fmt.Sprintf("field '%s': %v: '%s'", e.Name, e.Err, e.Value)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+type enumTable struct {
+	names  map[int64]string
+	values map[string]int64
+	sorted []string // names in order of value
+}
+
+// RegisterEnum registers the names of the values of an integer type,
+// causing fields of that type to be encoded and decoded by name.
+// The value of names must be a map[T]string where T is an integer type.
+// Registered types can be constrained with the oneof tag option.
+func RegisterEnum(names interface{}) error {
+	m := reflect.ValueOf(names)
+	if m.Kind() != reflect.Map || m.Type().Elem().Kind() != reflect.String {
+		return ErrInvalidArgument
+	}
+
+	t := m.Type().Key()
+
+	var toInt func(reflect.Value) int64
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		toInt = reflect.Value.Int
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		toInt = func(v reflect.Value) int64 { return int64(v.Uint()) }
+	default:
+		return ErrInvalidArgument
+	}
+
+	table := enumTable{
+		names:  make(map[int64]string, m.Len()),
+		values: make(map[string]int64, m.Len()),
+	}
+
+	keys := make([]int64, 0, m.Len())
+	for it := m.MapRange(); it.Next(); {
+		k, name := toInt(it.Key()), it.Value().String()
+		if _, exists := table.values[name]; exists {
+			return fmt.Errorf("duplicate enum name '%s'", name)
+		}
+		table.names[k] = name
+		table.values[name] = k
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		table.sorted = append(table.sorted, table.names[k])
+	}
+
+	structLock.Lock()
+	defer structLock.Unlock()
+	enumCache[t] = &table
+	// invalidate struct fields that may have been converted without the table
+	structCache = map[reflect.Type][]structField{}
+	return nil
+}
+
+// enumCodecOf must be called while holding structLock.
+func enumCodecOf(t reflect.Type, name string) converter {
+	if table, exists := enumCache[t]; exists {
+		return &enumCodec{table, name}
+	}
+	return nil
+}
+
+type enumCodec struct {
+	*enumTable
+	Name string
+}
+
+func (c *enumCodec) Decode(v reflect.Value, s string) error {
+	x, ok := c.values[s]
+	if !ok {
+		return &FieldError{Name: c.Name, Value: s, Allowed: c.sorted, Err: ErrNotOneOf}
+	}
+	if k := v.Kind(); k >= reflect.Uint && k <= reflect.Uint64 {
+		v.SetUint(uint64(x))
+	} else {
+		v.SetInt(x)
+	}
+	return nil
+}
+
+func (c *enumCodec) Encode(v reflect.Value) (string, error) {
+	var x int64
+	if k := v.Kind(); k >= reflect.Uint && k <= reflect.Uint64 {
+		x = int64(v.Uint())
+	} else {
+		x = v.Int()
+	}
+	if s, ok := c.names[x]; ok {
+		return s, nil
+	}
+	return "", &FieldError{Name: c.Name, Value: fmt.Sprint(x), Allowed: c.sorted, Err: ErrNotOneOf}
+}
+
+type oneOfCodec struct {
+	converter
+	Name    string
+	Allowed []string
+}
+
+func (c *oneOfCodec) check(s string) error {
+	for _, a := range c.Allowed {
+		if s == a {
+			return nil
+		}
+	}
+	return &FieldError{Name: c.Name, Value: s, Allowed: c.Allowed, Err: ErrNotOneOf}
+}
+
+func (c *oneOfCodec) Decode(v reflect.Value, s string) error {
+	if err := c.check(s); err != nil {
+		return err
+	}
+	return c.converter.Decode(v, s)
+}
+
+func (c *oneOfCodec) Encode(v reflect.Value) (string, error) {
+	s, err := c.converter.Encode(v)
+	if err != nil {
+		return "", err
+	} else if err := c.check(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
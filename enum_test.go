@@ -0,0 +1,110 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testStatus string
+
+type testColor uint8
+
+const (
+	testRed testColor = iota + 1
+	testGreen
+	testBlue
+)
+
+func init() {
+	if err := RegisterEnum(map[testColor]string{
+		testRed:   "red",
+		testGreen: "green",
+		testBlue:  "blue",
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterEnumInvalid(t *testing.T) {
+	if err := RegisterEnum(map[string]string{}); err != ErrInvalidArgument {
+		t.Error("string keys", err)
+	}
+	if err := RegisterEnum(map[int]int{}); err != ErrInvalidArgument {
+		t.Error("int values", err)
+	}
+	if err := RegisterEnum(map[int]string{1: "a", 2: "a"}); err == nil {
+		t.Error("duplicate names")
+	}
+}
+
+func TestDecodeOneOf(t *testing.T) {
+	type struc struct {
+		Status testStatus `csv:"status,oneof=active|suspended|closed"`
+		Color  testColor  `csv:"color,oneof=red|green"`
+		Any    *testColor `csv:"any"`
+	}
+
+	var data []struc
+	testdata := "status,color,any\nactive,green,blue\nclosed,red,"
+	if err := Unmarshal([]byte(testdata), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	blue := testBlue
+	expect := []struc{
+		{"active", testGreen, &blue},
+		{"closed", testRed, nil},
+	}
+
+	if !reflect.DeepEqual(data, expect) {
+		t.Fatal("should be equal", data)
+	}
+
+	for _, testdata := range []string{
+		"status,color,any\ndeleted,red,blue",
+		"status,color,any\nactive,blue,blue",
+		"status,color,any\nactive,red,purple",
+	} {
+		var fe *FieldError
+		if err := Unmarshal([]byte(testdata), &data); !errors.As(err, &fe) {
+			t.Error("expected FieldError", err)
+		} else if !errors.Is(err, ErrNotOneOf) || len(fe.Allowed) == 0 {
+			t.Error("expected ErrNotOneOf", err)
+		}
+	}
+}
+
+func TestEncodeOneOf(t *testing.T) {
+	type struc struct {
+		Status testStatus `csv:"status,oneof=active|closed"`
+		Color  testColor  `csv:"color"`
+	}
+
+	data := []struc{{"active", testRed}, {"closed", testBlue}}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(&data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "status,color\nactive,red\nclosed,blue\n" {
+		t.Fatal(b.String())
+	}
+
+	var fe *FieldError
+
+	data = []struc{{"unknown", testRed}}
+	if err := NewEncoder(&b).Encode(&data); !errors.As(err, &fe) || fe.Name != "status" {
+		t.Error("expected FieldError", err)
+	} else if strings.Join(fe.Allowed, "|") != "active|closed" {
+		t.Error(fe.Allowed)
+	}
+
+	data = []struc{{"active", 0}}
+	if err := NewEncoder(&b).Encode(&data); !errors.As(err, &fe) || fe.Name != "color" {
+		t.Error("expected FieldError", err)
+	} else if strings.Join(fe.Allowed, "|") != "red|green|blue" {
+		t.Error(fe.Allowed)
+	}
+}
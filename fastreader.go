@@ -0,0 +1,337 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+	"unsafe"
+)
+
+var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
+
+const (
+	fastReaderBufSize   = 64 << 10
+	fastReaderBlockSize = 64 << 10
+)
+
+// FastReader is a Reader that parses the same format as csv.Reader
+// and produces identical records, errors and positions, but allocates less.
+//
+// The fields of all records are stored in shared blocks of memory
+// instead of one allocation per record, so a retained field keeps
+// its block alive. The record slice is reused by every call to Read.
+//
+// It is the Reader returned by Dialect.NewReader and used by Decoder by default.
+type FastReader struct {
+	// Comma is the field delimiter. It is set to ',' by NewFastReader.
+	Comma rune
+
+	// Comment, if not 0, is the comment character.
+	// Lines beginning with the Comment character without preceding whitespace are ignored.
+	Comment rune
+
+	// LazyQuotes allows quotes to appear in unquoted fields
+	// and non-doubled quotes to appear in quoted fields.
+	LazyQuotes bool
+
+	// TrimLeadingSpace ignores leading white space in a field.
+	TrimLeadingSpace bool
+
+	rd     io.Reader
+	buf    []byte // read buffer
+	r, w   int    // read and write positions of buf
+	scan   int    // bytes of buf[r:w] known not to contain a newline
+	err    error  // error returned by rd
+	line   int    // number of lines read
+	offset int64  // number of bytes consumed
+
+	block     []byte // storage of the fields, only ever appended to
+	start     int    // start of the current record in block
+	indexes   []int  // end of each field relative to start
+	positions []fastPos
+	record    []string
+}
+
+type fastPos struct {
+	line, col int
+}
+
+// NewFastReader returns a FastReader that reads from r.
+func NewFastReader(r io.Reader) *FastReader {
+	return &FastReader{
+		Comma: ',',
+		rd:    r,
+	}
+}
+
+// Read reads one record from r. The record is a slice of strings
+// that is reused by the next call to Read. The strings are not modified.
+// Read returns io.EOF at the end of the input and *csv.ParseError on syntax errors.
+func (r *FastReader) Read() ([]string, error) {
+	return r.readRecord()
+}
+
+// FieldPos returns the line and column of the start of the field
+// with the given index in the record most recently returned by Read.
+// Numbering of lines and columns starts at 1; columns are counted in bytes.
+// It panics if field is out of range.
+func (r *FastReader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.positions) {
+		panic("out of range index passed to FieldPos")
+	}
+	p := &r.positions[field]
+	return p.line, p.col
+}
+
+// InputOffset returns the input stream byte offset of the end of the most recently read record.
+func (r *FastReader) InputOffset() int64 {
+	return r.offset
+}
+
+// readLine reads the next line including the newline,
+// with \r\n normalized to \n. The line is valid until the next call.
+func (r *FastReader) readLine() ([]byte, error) {
+	if r.buf == nil {
+		r.buf = make([]byte, fastReaderBufSize)
+	}
+
+	var line []byte
+	var err error
+	for {
+		if i := bytes.IndexByte(r.buf[r.r+r.scan:r.w], '\n'); i >= 0 {
+			end := r.r + r.scan + i + 1
+			line, r.r, r.scan = r.buf[r.r:end], end, 0
+			break
+		} else if r.err != nil {
+			line, err = r.buf[r.r:r.w], r.err
+			r.r, r.scan = r.w, 0
+			if r.err != io.EOF {
+				r.err = nil // report other errors once, like bufio.Reader
+			}
+			break
+		}
+
+		r.scan = r.w - r.r
+
+		// make room for more input
+		if r.r > 0 {
+			copy(r.buf, r.buf[r.r:r.w])
+			r.w -= r.r
+			r.r = 0
+		}
+		if r.w == len(r.buf) {
+			buf := make([]byte, 2*len(r.buf))
+			copy(buf, r.buf[:r.w])
+			r.buf = buf
+		}
+
+		n, err := r.rd.Read(r.buf[r.w:])
+		r.w += n
+		r.err = err
+	}
+
+	readSize := len(line)
+	if readSize > 0 && err == io.EOF {
+		err = nil
+		// for compatibility with csv.Reader, drop trailing \r before EOF
+		if line[readSize-1] == '\r' {
+			line = line[:readSize-1]
+		}
+	}
+
+	r.line++
+	r.offset += int64(readSize)
+
+	if n := len(line); n >= 2 && line[n-2] == '\r' && line[n-1] == '\n' {
+		line[n-2] = '\n'
+		line = line[:n-1]
+	}
+
+	return line, err
+}
+
+// appendField appends b to the current record in the block.
+func (r *FastReader) appendField(b ...byte) {
+	if len(r.block)+len(b) > cap(r.block) {
+		// move the current record to a new block
+		size := len(r.block) - r.start + len(b)
+		if size < fastReaderBlockSize/2 {
+			size = fastReaderBlockSize
+		} else {
+			size *= 2
+		}
+		block := make([]byte, 0, size)
+		r.block = append(block, r.block[r.start:]...)
+		r.start = 0
+	}
+	r.block = append(r.block, b...)
+}
+
+func (r *FastReader) endField(pos fastPos) {
+	r.indexes = append(r.indexes, len(r.block)-r.start)
+	r.positions = append(r.positions, pos)
+}
+
+func lengthNL(b []byte) int {
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		return 1
+	}
+	return 0
+}
+
+func nextRune(b []byte) rune {
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+func validDelim(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+// readRecord follows the algorithm of csv.Reader to produce identical results.
+func (r *FastReader) readRecord() ([]string, error) {
+	if r.Comma == r.Comment || !validDelim(r.Comma) || (r.Comment != 0 && !validDelim(r.Comment)) {
+		return nil, errInvalidDelim
+	}
+
+	// read line, skipping empty lines and comments
+	var line []byte
+	var errRead error
+	for errRead == nil {
+		line, errRead = r.readLine()
+		if r.Comment != 0 && nextRune(line) == r.Comment {
+			line = nil
+			continue
+		}
+		if errRead == nil && len(line) == lengthNL(line) {
+			line = nil
+			continue
+		}
+		break
+	}
+	if errRead == io.EOF {
+		return nil, errRead
+	}
+
+	var err error
+	const quoteLen = len(`"`)
+	commaLen := utf8.RuneLen(r.Comma)
+	recLine := r.line
+	r.start = len(r.block)
+	r.indexes = r.indexes[:0]
+	r.positions = r.positions[:0]
+	pos := fastPos{line: r.line, col: 1}
+
+parseField:
+	for {
+		if r.TrimLeadingSpace {
+			i := bytes.IndexFunc(line, func(r rune) bool {
+				return !unicode.IsSpace(r)
+			})
+			if i < 0 {
+				i = len(line)
+				pos.col -= lengthNL(line)
+			}
+			line = line[i:]
+			pos.col += i
+		}
+
+		if len(line) == 0 || line[0] != '"' {
+			// unquoted field
+			i := bytes.IndexRune(line, r.Comma)
+			field := line
+			if i >= 0 {
+				field = field[:i]
+			} else {
+				field = field[:len(field)-lengthNL(field)]
+			}
+			if !r.LazyQuotes {
+				if j := bytes.IndexByte(field, '"'); j >= 0 {
+					err = &csv.ParseError{StartLine: recLine, Line: r.line, Column: pos.col + j, Err: csv.ErrBareQuote}
+					break parseField
+				}
+			}
+			r.appendField(field...)
+			r.endField(pos)
+			if i >= 0 {
+				line = line[i+commaLen:]
+				pos.col += i + commaLen
+				continue parseField
+			}
+			break parseField
+		}
+
+		// quoted field
+		fieldPos := pos
+		line = line[quoteLen:]
+		pos.col += quoteLen
+		for {
+			if i := bytes.IndexByte(line, '"'); i >= 0 {
+				r.appendField(line[:i]...)
+				line = line[i+quoteLen:]
+				pos.col += i + quoteLen
+				switch rn := nextRune(line); {
+				case rn == '"': // escaped quote
+					r.appendField('"')
+					line = line[quoteLen:]
+					pos.col += quoteLen
+				case rn == r.Comma: // end of field
+					line = line[commaLen:]
+					pos.col += commaLen
+					r.endField(fieldPos)
+					continue parseField
+				case lengthNL(line) == len(line): // end of line
+					r.endField(fieldPos)
+					break parseField
+				case r.LazyQuotes: // bare quote
+					r.appendField('"')
+				default: // invalid non-escaped quote
+					err = &csv.ParseError{StartLine: recLine, Line: r.line, Column: pos.col - quoteLen, Err: csv.ErrQuote}
+					break parseField
+				}
+			} else if len(line) > 0 {
+				// the field continues on the next line
+				r.appendField(line...)
+				if errRead != nil {
+					break parseField
+				}
+				pos.col += len(line)
+				line, errRead = r.readLine()
+				if len(line) > 0 {
+					pos.line++
+					pos.col = 1
+				}
+				if errRead == io.EOF {
+					errRead = nil
+				}
+			} else {
+				// abrupt end of input
+				if !r.LazyQuotes && errRead == nil {
+					err = &csv.ParseError{StartLine: recLine, Line: pos.line, Column: pos.col, Err: csv.ErrQuote}
+					break parseField
+				}
+				r.endField(fieldPos)
+				break parseField
+			}
+		}
+	}
+
+	if err == nil {
+		err = errRead
+	}
+
+	// slice the fields out of the block, which is never modified below its length
+	fields := r.block[r.start:]
+	str := *(*string)(unsafe.Pointer(&fields))
+	r.record = r.record[:0]
+	var prev int
+	for _, idx := range r.indexes {
+		r.record = append(r.record, str[prev:idx])
+		prev = idx
+	}
+
+	return r.record, err
+}
@@ -0,0 +1,218 @@
+package csvbuddy
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var fastReaderTests = []struct {
+	Name             string
+	Input            string
+	Comma            rune
+	Comment          rune
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+}{
+	{Name: "Simple", Input: "a,b,c\n"},
+	{Name: "CRLF", Input: "a,b\r\nc,d\r\n"},
+	{Name: "BareCR", Input: "a,b\rc,d\r\n"},
+	{Name: "CRAtEOF", Input: "a,b\r"},
+	{Name: "NoTrailingNewline", Input: "a,b\nc,d"},
+	{Name: "EmptyInput", Input: ""},
+	{Name: "EmptyLines", Input: "\n\na,b\n\n\r\nc,d\n\n"},
+	{Name: "EmptyFields", Input: ",,\n,\n"},
+	{Name: "Quoted", Input: `"a","b,c","d"` + "\n"},
+	{Name: "QuotedEmpty", Input: `"",""` + "\n"},
+	{Name: "EscapedQuotes", Input: `"a""b","""",""""""` + "\n"},
+	{Name: "MultilineQuoted", Input: "\"a\nb\",c\n\"d\r\ne\r\nf\",g\n"},
+	{Name: "QuotedCRLFAtEnd", Input: "\"a\r\n\"\r\n"},
+	{Name: "QuotedFieldAtEOF", Input: `a,"b"`},
+	{Name: "BareQuote", Input: "a,b\"c\n"},
+	{Name: "BareQuoteLazy", Input: "a,b\"c\n", LazyQuotes: true},
+	{Name: "ExtraneousQuote", Input: `"a"b,c` + "\n"},
+	{Name: "ExtraneousQuoteLazy", Input: `"a"b,c` + "\n", LazyQuotes: true},
+	{Name: "UnterminatedQuote", Input: "a,\"b\nc\n"},
+	{Name: "UnterminatedQuoteLazy", Input: "a,\"b\nc\n", LazyQuotes: true},
+	{Name: "QuoteAtEOF", Input: `a,"`},
+	{Name: "QuoteAtEOFLazy", Input: `a,"`, LazyQuotes: true},
+	{Name: "ErrorOnSecondLine", Input: "a,b\nc,\"d\"e\n"},
+	{Name: "TrimLeadingSpace", Input: " a,  b,\t\"c\"\n \n", TrimLeadingSpace: true},
+	{Name: "TrimLeadingSpaceOnly", Input: "  \n", TrimLeadingSpace: true},
+	{Name: "LeadingSpaceQuote", Input: ` "a"` + "\n"},
+	{Name: "Comment", Input: "#x,y\na,b\n #c\n", Comment: '#'},
+	{Name: "Semicolon", Input: "a;b,c;\"d;e\"\n", Comma: ';'},
+	{Name: "Tab", Input: "a\tb\t\"c\td\"\n", Comma: '\t'},
+	{Name: "UnicodeComma", Input: "a€b€\"c€\"€d\n", Comma: '€'},
+	{Name: "UnicodeData", Input: "héllo,wörld\n\"日本\",語\n"},
+	{Name: "InvalidComma", Input: "a,b\n", Comma: '"'},
+	{Name: "SameCommaComment", Input: "a,b\n", Comment: ','},
+}
+
+// readAll reads all records of r and their positions until the first error.
+func readAll(r Reader) (records [][]string, positions [][2]int, offsets []int64, err error) {
+	for {
+		var record []string
+		if record, err = r.Read(); len(record) > 0 {
+			records = append(records, append([]string{}, record...))
+			for i := range record {
+				line, col := fieldPos(r, i)
+				positions = append(positions, [2]int{line, col})
+			}
+			offsets = append(offsets, inputOffset(r))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func testFastReaderConformance(t *testing.T, name, input string, configure func(*csv.Reader), newInput func(string) io.Reader) {
+	t.Helper()
+
+	cr := csv.NewReader(newInput(input))
+	cr.FieldsPerRecord = -1
+	configure(cr)
+
+	fr := NewFastReader(newInput(input))
+	fr.Comma = cr.Comma
+	fr.Comment = cr.Comment
+	fr.LazyQuotes = cr.LazyQuotes
+	fr.TrimLeadingSpace = cr.TrimLeadingSpace
+
+	wantRecords, wantPositions, wantOffsets, wantErr := readAll(cr)
+	records, positions, offsets, err := readAll(fr)
+
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Errorf("%s: records %q, want %q", name, records, wantRecords)
+	}
+	if !reflect.DeepEqual(positions, wantPositions) {
+		t.Errorf("%s: positions %v, want %v", name, positions, wantPositions)
+	}
+	if !reflect.DeepEqual(offsets, wantOffsets) {
+		t.Errorf("%s: offsets %v, want %v", name, offsets, wantOffsets)
+	}
+
+	var perr, wantPerr *csv.ParseError
+	if errors.As(wantErr, &wantPerr) {
+		if !errors.As(err, &perr) || *perr != *wantPerr {
+			t.Errorf("%s: error %v, want %v", name, err, wantErr)
+		}
+	} else if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("%s: error %v, want %v", name, err, wantErr)
+	}
+}
+
+func TestFastReaderConformance(t *testing.T) {
+	newInputs := map[string]func(string) io.Reader{
+		"":         func(s string) io.Reader { return strings.NewReader(s) },
+		"/onebyte": func(s string) io.Reader { return &oneByteReader{[]byte(s)} },
+		"/error": func(s string) io.Reader {
+			return io.MultiReader(strings.NewReader(s), iotest.ErrReader(errors.New("read error")))
+		},
+	}
+
+	for _, test := range fastReaderTests {
+		test := test
+		configure := func(cr *csv.Reader) {
+			if test.Comma != 0 {
+				cr.Comma = test.Comma
+			}
+			cr.Comment = test.Comment
+			cr.LazyQuotes = test.LazyQuotes
+			cr.TrimLeadingSpace = test.TrimLeadingSpace
+		}
+		for suffix, newInput := range newInputs {
+			testFastReaderConformance(t, test.Name+suffix, test.Input, configure, newInput)
+		}
+	}
+}
+
+func TestFastReaderConformanceRandom(t *testing.T) {
+	alphabet := []string{"a", "é", ",", ";", "\"", "\"\"", " ", "\n", "\r", "\r\n", "#"}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		var b strings.Builder
+		for n := rnd.Intn(40); n > 0; n-- {
+			b.WriteString(alphabet[rnd.Intn(len(alphabet))])
+		}
+
+		lazy, trim, comment := rnd.Intn(2) == 0, rnd.Intn(2) == 0, rnd.Intn(2) == 0
+		configure := func(cr *csv.Reader) {
+			cr.LazyQuotes = lazy
+			cr.TrimLeadingSpace = trim
+			if comment {
+				cr.Comment = '#'
+			}
+		}
+		testFastReaderConformance(t, strings.ReplaceAll(b.String(), "\n", `\n`), b.String(), configure, func(s string) io.Reader {
+			return strings.NewReader(s)
+		})
+	}
+}
+
+func TestFastReaderLongRecords(t *testing.T) {
+	long := strings.Repeat("x", 3*fastReaderBlockSize)
+	input := "a,b\n\"" + long + "\n" + long + "\",c\n" + long + ",d\ne,f\n"
+
+	configure := func(*csv.Reader) {}
+	testFastReaderConformance(t, "LongRecords", input, configure, func(s string) io.Reader {
+		return strings.NewReader(s)
+	})
+}
+
+func TestFastReaderRetainedFields(t *testing.T) {
+	r := NewFastReader(strings.NewReader("a,b\nc,d\ne,f\n"))
+
+	var fields []string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, record...)
+	}
+
+	if strings.Join(fields, "") != "abcdef" {
+		t.Fatal(fields)
+	}
+}
+
+func TestDecodeFastReader(t *testing.T) {
+	if r, ok := (Dialect{Comma: ','}).NewReader(nil).(*bomOffsetReader); !ok {
+		t.Fatal("expected bomOffsetReader")
+	} else if _, ok := r.Reader.(*FastReader); !ok {
+		t.Fatal("expected FastReader")
+	}
+
+	// the default Reader and csv.Reader produce the same results
+	for _, readerFunc := range []ReaderFunc{nil, NewReader} {
+		var data []testPeriod
+		d := NewDecoder(strings.NewReader("start,end\n1,2\n\"3\",4\n"))
+		if readerFunc != nil {
+			d.SetReaderFunc(readerFunc)
+		}
+		if err := d.Decode(&data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) {
+			t.Fatal("not equal", data)
+		}
+
+		d = NewDecoder(strings.NewReader("start,end\n1,2\n3,x\n"))
+		if readerFunc != nil {
+			d.SetReaderFunc(readerFunc)
+		}
+		var perr *csv.ParseError
+		if err := d.Decode(&data); !errors.As(err, &perr) || perr.Line != 3 || perr.Column != 3 {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package csvbuddy
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrFieldWidth signals that a value does not fit in its fixed-width column.
+var ErrFieldWidth = errors.New("value exceeds column width")
+
+// Alignment is the alignment of a value within a fixed-width column.
+type Alignment int
+
+const (
+	// AlignLeft pads values on the right. This is the default.
+	AlignLeft Alignment = iota
+
+	// AlignRight pads values on the left.
+	AlignRight
+)
+
+// FixedWidthColumn describes a column of a fixed-width text file.
+type FixedWidthColumn struct {
+	Name  string    // column name
+	Width int       // width in characters
+	Align Alignment // alignment of the value
+	Pad   rune      // padding character, a space if 0
+}
+
+func (c FixedWidthColumn) pad() rune {
+	if c.Pad == 0 {
+		return ' '
+	}
+	return c.Pad
+}
+
+// FixedWidthLayout is the sequence of columns of a fixed-width text file.
+type FixedWidthLayout []FixedWidthColumn
+
+// FixedWidthLayoutOf returns the layout declared by the struct tags of v,
+// which must be a pointer to a slice of structs or a pointer to a slice of pointers to structs.
+// Every field must have the width option and may have the align and pad options:
+//
+//	Account string `csv:"acct,width=10,align=right,pad=0"`
+func FixedWidthLayoutOf(v interface{}) (FixedWidthLayout, error) {
+	t := sliceStructType(reflect.TypeOf(v))
+	if t == nil {
+		return nil, ErrInvalidArgument
+	}
+
+	fields, err := structFieldsOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := make(FixedWidthLayout, len(fields))
+	for i, field := range fields {
+		tag := parseTag(t.FieldByIndex(field.Index).Tag.Get("csv"))
+		col := FixedWidthColumn{Name: field.Name}
+
+		if width, ok := tag.Lookup("width"); !ok {
+			return nil, fmt.Errorf("field '%s': missing width", field.Name)
+		} else if col.Width, err = strconv.Atoi(width); err != nil {
+			return nil, fmt.Errorf("field '%s': invalid width: %w", field.Name, err)
+		}
+
+		switch align, _ := tag.Lookup("align"); align {
+		case "", "left":
+		case "right":
+			col.Align = AlignRight
+		default:
+			return nil, fmt.Errorf("field '%s': invalid align '%s'", field.Name, align)
+		}
+
+		if pad, ok := tag.Lookup("pad"); ok {
+			if utf8.RuneCountInString(pad) != 1 {
+				return nil, fmt.Errorf("field '%s': invalid pad '%s'", field.Name, pad)
+			}
+			col.Pad, _ = utf8.DecodeRuneInString(pad)
+		}
+
+		layout[i] = col
+	}
+
+	return layout, layout.validate()
+}
+
+func (l FixedWidthLayout) validate() error {
+	for _, col := range l {
+		if col.Width <= 0 {
+			return fmt.Errorf("csv: column '%s' has invalid width %d", col.Name, col.Width)
+		} else if col.Align != AlignLeft && col.Align != AlignRight {
+			return fmt.Errorf("csv: column '%s' has invalid alignment", col.Name)
+		} else if pad := col.pad(); pad == '\r' || pad == '\n' || !utf8.ValidRune(pad) {
+			return fmt.Errorf("csv: column '%s' has invalid padding %q", col.Name, pad)
+		}
+	}
+	return nil
+}
+
+func (l FixedWidthLayout) names() []string {
+	names := make([]string, len(l))
+	for i, col := range l {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// FixedWidthReader is a Reader that reads records from a fixed-width text file.
+// Every line is a record that is divided into fields by the column widths
+// of the layout, counted in characters. Padding is removed from the fields,
+// except that a zero-padded column that consists only of padding reads as "0".
+// Empty lines are skipped and characters beyond the last column are ignored.
+type FixedWidthReader struct {
+	r       *bufio.Reader
+	layout  FixedWidthLayout
+	line    int
+	offset  int64
+	buf     []byte
+	record  []string
+	columns []int
+}
+
+// NewFixedWidthReader returns a FixedWidthReader that reads from r using layout.
+func NewFixedWidthReader(r io.Reader, layout FixedWidthLayout) *FixedWidthReader {
+	return &FixedWidthReader{
+		r:       bufio.NewReader(r),
+		layout:  layout,
+		record:  make([]string, len(layout)),
+		columns: make([]int, len(layout)),
+	}
+}
+
+// Read reads one record. The record slice is reused by the next call to Read.
+func (r *FixedWidthReader) Read() ([]string, error) {
+	if err := r.layout.validate(); err != nil {
+		return nil, err
+	}
+
+	for {
+		line, err := r.readLine()
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		} else if len(line) == 0 {
+			continue
+		}
+
+		s := string(line)
+		var pos int
+		for i, col := range r.layout {
+			start := pos
+			for n := 0; n < col.Width && pos < len(s); n++ {
+				_, size := utf8.DecodeRuneInString(s[pos:])
+				pos += size
+			}
+			r.record[i] = col.trim(s[start:pos])
+			r.columns[i] = start + 1
+		}
+
+		return r.record, nil
+	}
+}
+
+// readLine reads the next line without the line terminator.
+func (r *FixedWidthReader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		r.buf = append(r.buf[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = r.r.ReadSlice('\n')
+			r.buf = append(r.buf, line...)
+		}
+		line = r.buf
+	}
+
+	if len(line) > 0 {
+		r.line++
+		r.offset += int64(len(line))
+	}
+
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+
+	return line, err
+}
+
+func (c FixedWidthColumn) trim(field string) string {
+	pad := c.pad()
+	isPad := func(r rune) bool { return r == pad }
+
+	if c.Align == AlignRight {
+		if v := strings.TrimLeftFunc(field, isPad); v != "" || pad != '0' || field == "" {
+			return v
+		}
+		return "0"
+	}
+
+	return strings.TrimRightFunc(field, isPad)
+}
+
+// FieldPos returns the line and column of the field with the given index
+// in the record most recently returned by Read.
+// Numbering of lines and columns starts at 1; columns are counted in bytes.
+func (r *FixedWidthReader) FieldPos(field int) (line, column int) {
+	return r.line, r.columns[field]
+}
+
+// InputOffset returns the input stream byte offset of the end of the most recently read record.
+func (r *FixedWidthReader) InputOffset() int64 {
+	return r.offset
+}
+
+// FixedWidthWriter is a Writer that writes records to a fixed-width text file.
+// Every field is padded to the width of its column. Zero padding of a right-aligned
+// column is inserted after the sign of the value. Values that are wider than
+// their column return an error wrapping ErrFieldWidth.
+type FixedWidthWriter struct {
+	// UseCRLF terminates lines with \r\n instead of \n.
+	UseCRLF bool
+
+	w      *bufio.Writer
+	layout FixedWidthLayout
+	buf    []byte
+}
+
+// NewFixedWidthWriter returns a FixedWidthWriter that writes to w using layout.
+func NewFixedWidthWriter(w io.Writer, layout FixedWidthLayout) *FixedWidthWriter {
+	return &FixedWidthWriter{
+		w:      bufio.NewWriter(w),
+		layout: layout,
+	}
+}
+
+// Write writes one record, which must have a field for every column.
+// Writes are buffered, so Flush must be called to ensure that the record is written.
+func (w *FixedWidthWriter) Write(record []string) error {
+	if err := w.layout.validate(); err != nil {
+		return err
+	} else if len(record) != len(w.layout) {
+		return fmt.Errorf("csv: %w", csv.ErrFieldCount)
+	}
+
+	w.buf = w.buf[:0]
+	for i, col := range w.layout {
+		value := record[i]
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("csv: column '%s' contains a line break", col.Name)
+		}
+
+		n := utf8.RuneCountInString(value)
+		if n > col.Width {
+			return fmt.Errorf("%w: '%s' is %d characters wide: %q", ErrFieldWidth, col.Name, col.Width, value)
+		}
+
+		pad := col.pad()
+		switch {
+		case col.Align == AlignLeft:
+			w.buf = append(w.buf, value...)
+			w.buf = appendPadding(w.buf, pad, col.Width-n)
+		case pad == '0' && value != "" && (value[0] == '-' || value[0] == '+'):
+			w.buf = append(w.buf, value[0])
+			w.buf = appendPadding(w.buf, pad, col.Width-n)
+			w.buf = append(w.buf, value[1:]...)
+		default:
+			w.buf = appendPadding(w.buf, pad, col.Width-n)
+			w.buf = append(w.buf, value...)
+		}
+	}
+
+	if w.UseCRLF {
+		w.buf = append(w.buf, '\r')
+	}
+	w.buf = append(w.buf, '\n')
+
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+func appendPadding(b []byte, pad rune, n int) []byte {
+	for ; n > 0; n-- {
+		b = utf8.AppendRune(b, pad)
+	}
+	return b
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *FixedWidthWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// SetFixedWidth causes the Decoder to read a fixed-width text file using layout.
+// The file has no header; the columns are matched to the struct fields by name.
+// It replaces the ReaderFunc.
+func (d *Decoder) SetFixedWidth(layout FixedWidthLayout) {
+	d.readerFunc = func(r io.Reader) Reader { return NewFixedWidthReader(r, layout) }
+	d.customReader = true
+	d.readerFuncSet = true
+	d.header = layout.names()
+	d.skipHeader = true
+}
+
+// SetFixedWidth causes the Encoder to write a fixed-width text file using layout.
+// No header is written; the columns are matched to the struct fields by name.
+// It replaces the WriterFunc and the header.
+func (e *Encoder) SetFixedWidth(layout FixedWidthLayout) {
+	e.writerFunc = func(w io.Writer) Writer { return NewFixedWidthWriter(w, layout) }
+	e.header = layout.names()
+	e.skipHeader = true
+}
@@ -0,0 +1,159 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testAccount struct {
+	Name    string  `csv:"name,width=8"`
+	Account int     `csv:"acct,width=6,align=right,pad=0"`
+	Balance float64 `csv:"balance,width=9,align=right,prec=2"`
+}
+
+func TestFixedWidthLayoutOf(t *testing.T) {
+	layout, err := FixedWidthLayoutOf(&[]testAccount{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := FixedWidthLayout{
+		{Name: "name", Width: 8},
+		{Name: "acct", Width: 6, Align: AlignRight, Pad: '0'},
+		{Name: "balance", Width: 9, Align: AlignRight},
+	}
+	if !reflect.DeepEqual(layout, expected) {
+		t.Fatal(layout)
+	}
+
+	var invalid []struct {
+		A string `csv:"a,width=x"`
+	}
+	if _, err := FixedWidthLayoutOf(&invalid); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var missing []struct {
+		A string `csv:"a"`
+	}
+	if _, err := FixedWidthLayoutOf(&missing); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestFixedWidthRoundTrip(t *testing.T) {
+	layout, err := FixedWidthLayoutOf(&[]testAccount{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []testAccount{
+		{"Stan", 42, 1234.5},
+		{"Kyle", -7, -0.25},
+		{"Cartman", 0, 0},
+	}
+
+	text := "" +
+		"Stan    000042  1234.50\r\n" +
+		"Kyle    -00007    -0.25\r\n" +
+		"Cartman 000000     0.00\r\n"
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetFixedWidth(layout)
+	e.SetWriterFunc(func(w io.Writer) Writer {
+		fw := NewFixedWidthWriter(w, layout)
+		fw.UseCRLF = true
+		return fw
+	})
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != text {
+		t.Fatalf("%q", b.String())
+	}
+
+	var data2 []testAccount
+	d := NewDecoder(&b)
+	d.SetFixedWidth(layout)
+	if err := d.Decode(&data2); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, data2) {
+		t.Fatal("not equal", data2)
+	}
+}
+
+func TestFixedWidthLayoutOrder(t *testing.T) {
+	layout := FixedWidthLayout{
+		{Name: "end", Width: 3, Align: AlignRight},
+		{Name: "filler", Width: 2},
+		{Name: "start", Width: 3, Align: AlignRight},
+	}
+
+	var data []testPeriod
+	d := NewDecoder(strings.NewReader("  2xx  1\n\n  4    3 extra\n"))
+	d.SetFixedWidth(layout)
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) {
+		t.Fatal("not equal", data)
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetFixedWidth(layout)
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "  2    1\n  4    3\n" {
+		t.Fatalf("%q", b.String())
+	}
+}
+
+func TestFixedWidthReader(t *testing.T) {
+	layout := FixedWidthLayout{
+		{Name: "a", Width: 3},
+		{Name: "b", Width: 2, Pad: '*'},
+	}
+
+	r := NewFixedWidthReader(strings.NewReader("héj**\n\nab"), layout)
+
+	if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, []string{"héj", ""}) {
+		t.Fatal(record, err)
+	} else if line, col := r.FieldPos(1); line != 1 || col != 5 {
+		t.Fatal(line, col)
+	} else if offset := r.InputOffset(); offset != 7 {
+		t.Fatal(offset)
+	}
+
+	if record, err := r.Read(); err != nil || !reflect.DeepEqual(record, []string{"ab", ""}) {
+		t.Fatal(record, err)
+	} else if line, col := r.FieldPos(0); line != 3 || col != 1 {
+		t.Fatal(line, col)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatal(err)
+	}
+}
+
+func TestFixedWidthWriterErrors(t *testing.T) {
+	layout := FixedWidthLayout{{Name: "a", Width: 3}}
+	w := NewFixedWidthWriter(io.Discard, layout)
+
+	if err := w.Write([]string{"abcd"}); !errors.Is(err, ErrFieldWidth) {
+		t.Fatal(err)
+	} else if err := w.Write([]string{"a", "b"}); !errors.Is(err, csv.ErrFieldCount) {
+		t.Fatal(err)
+	} else if err := w.Write([]string{"a\n"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	w = NewFixedWidthWriter(io.Discard, FixedWidthLayout{{Name: "a"}})
+	if err := w.Write([]string{"a"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
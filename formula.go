@@ -0,0 +1,60 @@
+package csvbuddy
+
+import (
+	"reflect"
+	"strings"
+)
+
+// formulaPrefixes are the characters that start a formula in spreadsheet applications.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes s with a single quote if a spreadsheet application
+// would interpret it as a formula. Decimal numbers are not formulas.
+func escapeFormula(s string) string {
+	if s != "" && strings.IndexByte(formulaPrefixes, s[0]) >= 0 && !isDecimal(s) {
+		return "'" + s
+	}
+	return s
+}
+
+// formulaColumns reports for each column of header whether its values are escaped.
+// Columns of text fields are escaped unless the field has the formula tag option.
+// Columns that do not belong to a field of structType are escaped as well,
+// because their values are produced by a CSVRecordMarshaler.
+func formulaColumns(structType reflect.Type, header []string) []bool {
+	fields, _ := structFieldsOf(structType)
+
+	byName := make(map[string]*structField, len(fields))
+	for i := range fields {
+		byName[fields[i].Name] = &fields[i]
+	}
+
+	escapes := make([]bool, len(header))
+	for i, name := range header {
+		field, ok := byName[name]
+		escapes[i] = !ok || (!field.Formula && isTextConverter(field.converter))
+	}
+	return escapes
+}
+
+// isTextConverter reports whether c encodes text rather than numbers or booleans.
+func isTextConverter(c converter) bool {
+	switch c := c.(type) {
+	case *ptrCodec:
+		return isTextConverter(c.converter)
+	case *oneOfCodec:
+		return isTextConverter(c.converter)
+	case *stringCodec, *byteSliceCodec, *textCodec, *fieldCodec:
+		return true
+	}
+	return false
+}
+
+// SetEscapeFormulas causes the Encoder to neutralize values that spreadsheet
+// applications would execute as formulas, which start with
+// '=', '+', '-', '@', tab or carriage return, by prefixing them with a single quote.
+// Only string, []byte, encoding.TextMarshaler and CSVFieldMarshaler fields
+// are escaped, except decimal numbers and fields with the formula tag option:
+//
+//	Total string `csv:"total,formula"`
+func (e *Encoder) SetEscapeFormulas(enabled bool) { e.escapeFormulas = enabled }
@@ -0,0 +1,89 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	for _, test := range []struct {
+		Value, Escaped string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{"=1+2", "'=1+2"},
+		{"+1+2", "'+1+2"},
+		{"-2+3+cmd|' /C calc'!A0", "'-2+3+cmd|' /C calc'!A0"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"-5", "-5"},
+		{"+1.5e3", "+1.5e3"},
+		{"a=1", "a=1"},
+	} {
+		if s := escapeFormula(test.Value); s != test.Escaped {
+			t.Errorf("%q: %q", test.Value, s)
+		}
+	}
+}
+
+type testFormulaRow struct {
+	Name    string  `csv:"name"`
+	Comment *string `csv:"comment"`
+	Raw     string  `csv:"raw,formula"`
+	Amount  int     `csv:"amount"`
+	Ratio   float64 `csv:"ratio,fmt=g"`
+	Bytes   []byte  `csv:"bytes"`
+	IP      net.IP  `csv:"ip"`
+}
+
+func TestEncodeEscapeFormulas(t *testing.T) {
+	comment := "@evil"
+	data := []testFormulaRow{
+		{"=HYPERLINK(\"x\")", &comment, "=SUM(A1:A2)", -3, -1e100, []byte("+x"), net.IPv4(1, 2, 3, 4)},
+		{"-10", nil, "", 0, 0, nil, nil},
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetEscapeFormulas(true)
+	e.SetMapFunc(func(name, value string) string {
+		if name == "name" && value == "-10" {
+			return "-x"
+		}
+		return value
+	})
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"name,comment,raw,amount,ratio,bytes,ip\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",'@evil,=SUM(A1:A2),-3,-1e+100,'+x,1.2.3.4\n" +
+		"'-x,,,0,0,,\n"
+	if b.String() != expected {
+		t.Fatalf("%q", b.String())
+	}
+}
+
+type testFormulaMarshaler struct {
+	Name   string
+	Amount int
+}
+
+func (r *testFormulaMarshaler) MarshalCSVRecord(header []string) ([]string, error) {
+	return []string{r.Name, "-1", "=extra"}, nil
+}
+
+func TestEncodeEscapeFormulasRecordMarshaler(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetHeader([]string{"Name", "Amount", "Extra"})
+	e.SetEscapeFormulas(true)
+	if err := e.Encode(&[]testFormulaMarshaler{{Name: "=1"}}); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "Name,Amount,Extra\n'=1,-1,'=extra\n" {
+		t.Fatalf("%q", b.String())
+	}
+}
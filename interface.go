@@ -2,10 +2,24 @@ package csvbuddy
 
 import (
 	"encoding/csv"
+	"fmt"
 	"io"
+	"reflect"
 )
 
-func fieldPos(r Reader, field int) (line int, column int) {
+var (
+	validatorType     = reflect.TypeOf((*Validator)(nil)).Elem()
+	beforeEncoderType = reflect.TypeOf((*BeforeEncoder)(nil)).Elem()
+
+	recordMarshalerType   = reflect.TypeOf((*CSVRecordMarshaler)(nil)).Elem()
+	recordUnmarshalerType = reflect.TypeOf((*CSVRecordUnmarshaler)(nil)).Elem()
+	columnCounterType     = reflect.TypeOf((*CSVColumnCounter)(nil)).Elem()
+
+	fieldMarshalerType   = reflect.TypeOf((*CSVFieldMarshaler)(nil)).Elem()
+	fieldUnmarshalerType = reflect.TypeOf((*CSVFieldUnmarshaler)(nil)).Elem()
+)
+
+func fieldPos(r interface{}, field int) (line int, column int) {
 	if fp, ok := r.(interface{ FieldPos(int) (int, int) }); ok {
 		return fp.FieldPos(field)
 	}
@@ -19,8 +33,10 @@ type Reader interface {
 }
 
 // NewReader returns a new csv.Reader that reads from r.
+// A UTF-8 byte order mark at the start of r is skipped
+// and not counted by InputOffset.
 func NewReader(r io.Reader) Reader {
-	cr := csv.NewReader(r)
+	cr := csv.NewReader(&bomReader{r: r})
 	cr.FieldsPerRecord = -1 // fields are checked by Decoder
 	cr.ReuseRecord = true
 	return cr
@@ -46,3 +62,69 @@ type ReaderFunc func(io.Reader) Reader
 
 // WriterFunc is a function that returns a Writer that writes to an output stream.
 type WriterFunc func(io.Writer) Writer
+
+// Validator is implemented by row types that validate themselves.
+// The Decoder calls CSVValidate after each row is decoded.
+// Use it to check rules that span multiple fields.
+type Validator interface {
+	CSVValidate() error
+}
+
+// BeforeEncoder is implemented by row types that prepare themselves for encoding.
+// The Encoder calls BeforeCSVEncode before each row is encoded.
+type BeforeEncoder interface {
+	BeforeCSVEncode() error
+}
+
+// CSVRecordMarshaler is implemented by row types that encode themselves to a record.
+// The Encoder calls MarshalCSVRecord instead of encoding the struct fields.
+// The returned record must be in the same order as the header.
+type CSVRecordMarshaler interface {
+	MarshalCSVRecord(header []string) ([]string, error)
+}
+
+// CSVRecordUnmarshaler is implemented by row types that decode themselves from a record.
+// The Decoder calls UnmarshalCSVRecord instead of decoding the struct fields.
+// The record may be reused by the next call and must be copied to be retained.
+// Return a ColumnError to report the position of the column that failed to decode.
+// DisallowUnknownFields and DisallowShortFields compare the length of the record
+// against the length of the header, unless the row type implements CSVColumnCounter.
+type CSVRecordUnmarshaler interface {
+	UnmarshalCSVRecord(header, record []string) error
+}
+
+// CSVColumnCounter is implemented by row types generated by csvbuddy-gen
+// to report the number of struct fields that they decode.
+// DisallowUnknownFields and DisallowShortFields compare records against it.
+// The Decoder and Encoder reject generated types that would ignore
+// a BoolFormat or a registered enumeration.
+type CSVColumnCounter interface {
+	CSVColumnCount() int
+}
+
+// ColumnError reports the column of a record that failed to decode or encode.
+type ColumnError struct {
+	Column int    // index of the column in the record
+	Name   string // name of the column
+	Err    error
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("column '%s': %v", e.Name, e.Err)
+}
+
+func (e *ColumnError) Unwrap() error { return e.Err }
+
+// CSVFieldMarshaler is implemented by field types that encode themselves
+// depending on the column. It takes precedence over encoding.TextMarshaler.
+// The tag holds the column name and the options of the struct field tag.
+type CSVFieldMarshaler interface {
+	MarshalCSVField(tag FieldTag) (string, error)
+}
+
+// CSVFieldUnmarshaler is implemented by field types that decode themselves
+// depending on the column. It takes precedence over encoding.TextUnmarshaler.
+// The tag holds the column name and the options of the struct field tag.
+type CSVFieldUnmarshaler interface {
+	UnmarshalCSVField(tag FieldTag, value string) error
+}
@@ -1,7 +1,119 @@
 package csvbuddy
 
-import "testing"
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
 
 func TestNewReader(t *testing.T) {
-	_ = NewReader(nil)
+	if _, ok := NewReader(nil).(*csv.Reader); !ok {
+		t.Fatal("expected *csv.Reader")
+	}
+}
+
+// testPoint encodes its coordinates as a single "x;y" column.
+type testPoint struct {
+	X, Y int
+	Ch   chan int // unsupported field type that is never reflected
+}
+
+func (p *testPoint) MarshalCSVRecord(header []string) ([]string, error) {
+	if p.X < 0 {
+		return nil, errors.New("negative")
+	}
+	return []string{fmt.Sprintf("%d;%d", p.X, p.Y)}, nil
+}
+
+func (p *testPoint) UnmarshalCSVRecord(header, record []string) error {
+	_, err := fmt.Sscanf(record[0], "%d;%d", &p.X, &p.Y)
+	return err
+}
+
+func TestRecordMarshaler(t *testing.T) {
+	data := []testPoint{{X: 1, Y: 2}, {X: 3, Y: 4}}
+
+	var b bytes.Buffer
+
+	e := NewEncoder(&b)
+	e.SetHeader([]string{"point"})
+
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "point\n1;2\n3;4\n" {
+		t.Fatal(b.String())
+	}
+
+	var pe *csv.ParseError
+	data = []testPoint{{X: 1}, {X: -1}}
+	if err := e.Encode(&data); !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatal("expected error on line 3", err)
+	}
+}
+
+func TestRecordUnmarshaler(t *testing.T) {
+	var data []testPoint
+	if err := Unmarshal([]byte("point\n1;2\n3;4\n"), &data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPoint{{X: 1, Y: 2}, {X: 3, Y: 4}}) {
+		t.Fatal(data)
+	}
+
+	var row testPoint
+	iter, err := NewDecoder(strings.NewReader("point\n5;6\nx")).Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	} else if !iter.Scan() || row.X != 5 || row.Y != 6 {
+		t.Fatal(row)
+	}
+
+	var pe *csv.ParseError
+	if iter.Scan() {
+		t.Fatal("expected error")
+	} else if err := iter.Err(); !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatal("expected error on line 3", err)
+	}
+}
+
+// testPair decodes two columns like code generated by csvbuddy-gen.
+type testPair struct {
+	A, B int
+}
+
+func (p *testPair) CSVColumnCount() int { return 2 }
+
+func (p *testPair) UnmarshalCSVRecord(header, record []string) (err error) {
+	for i, h := range header {
+		switch h {
+		case "a":
+			p.A, err = strconv.Atoi(record[i])
+		case "b":
+			p.B, err = strconv.Atoi(record[i])
+		}
+		if err != nil {
+			return &ColumnError{Column: i, Name: h, Err: err}
+		}
+	}
+	return nil
+}
+
+func TestColumnCounter(t *testing.T) {
+	var data []testPair
+	d := NewDecoder(strings.NewReader("a,b,c\n1,2,3\n"))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&data); !errors.Is(err, csv.ErrFieldCount) {
+		t.Fatal("expected ErrFieldCount", err)
+	}
+
+	var pe *csv.ParseError
+	if err := Unmarshal([]byte("a,b\n1,x\n"), &data); !errors.As(err, &pe) || pe.Line != 2 || pe.Column != 3 {
+		t.Fatal("expected error on line 2, column 3", err)
+	} else if _, ok := pe.Err.(*strconv.NumError); !ok {
+		t.Fatal("expected NumError", pe.Err)
+	}
 }
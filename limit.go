@@ -0,0 +1,250 @@
+package csvbuddy
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits bounds the size of the input accepted by a Decoder.
+// A zero value means that the limit is not enforced.
+//
+// The default Reader and the Readers of dialects without LazyQuotes
+// stop reading the input as soon as a record exceeds the byte, field or column limits,
+// which bounds the memory used by a single record. Custom Readers set by
+// SetReaderFunc are checked after each record is read, so they bound
+// the size of the records but not the memory used to read them.
+type Limits struct {
+	// MaxRows is the maximum number of rows, not counting the header.
+	MaxRows int
+
+	// MaxRecordBytes is the maximum number of bytes of a record.
+	// The default Reader counts the bytes of the input including quotes
+	// and separators, but excluding the line terminator.
+	// Custom Readers are limited by the total length of the fields.
+	MaxRecordBytes int
+
+	// MaxFieldBytes is the maximum number of bytes of a field.
+	MaxFieldBytes int
+
+	// MaxColumns is the maximum number of fields of a record.
+	MaxColumns int
+}
+
+// LimitError signals that the input exceeds one of the Limits of the Decoder.
+type LimitError struct {
+	Limit string // "rows", "record bytes", "field bytes" or "columns"
+	Max   int    // value of the limit
+	Line  int    // line of the record that exceeds the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("record on line %d: exceeds limit of %d %s", e.Line, e.Max, e.Limit)
+}
+
+// limitReader checks the records read by a Reader against the limits.
+type limitReader struct {
+	Reader
+	limits Limits
+}
+
+func (r *limitReader) Read() ([]string, error) {
+	record, err := r.Reader.Read()
+	if err != nil {
+		return record, err
+	}
+
+	limit, max := "", 0
+	if r.limits.MaxColumns > 0 && len(record) > r.limits.MaxColumns {
+		limit, max = "columns", r.limits.MaxColumns
+	} else if r.limits.MaxFieldBytes > 0 || r.limits.MaxRecordBytes > 0 {
+		var n int
+		for _, field := range record {
+			if n += len(field); r.limits.MaxFieldBytes > 0 && len(field) > r.limits.MaxFieldBytes {
+				limit, max = "field bytes", r.limits.MaxFieldBytes
+				break
+			}
+		}
+		if limit == "" && r.limits.MaxRecordBytes > 0 && n > r.limits.MaxRecordBytes {
+			limit, max = "record bytes", r.limits.MaxRecordBytes
+		}
+	}
+
+	if limit != "" {
+		line, _ := fieldPos(r.Reader, 0)
+		return nil, &LimitError{Limit: limit, Max: max, Line: line}
+	}
+
+	return record, nil
+}
+
+func (r *limitReader) FieldPos(field int) (line, column int) {
+	return fieldPos(r.Reader, field)
+}
+
+func (r *limitReader) InputOffset() int64 {
+	return inputOffset(r.Reader)
+}
+
+// byteLimitReader stops reading when a record of the CSV input stream
+// exceeds the byte, field or column limits, which bounds the memory used by a single record.
+// Records end at newlines that are not enclosed in quotes and fields end at delimiters
+// that are not enclosed in quotes. Field bytes exclude quotes and carriage returns,
+// and leading white space if it is trimmed, so that they never exceed the length of the field.
+type byteLimitReader struct {
+	r       io.Reader
+	limits  Limits
+	comma   []byte // UTF-8 encoding of the delimiter
+	comment []byte // UTF-8 encoding of the comment character, nil if none
+	trim    bool   // leading white space of fields is trimmed
+
+	n         int  // number of bytes of the current record
+	field     int  // number of bytes of the current field
+	columns   int  // number of fields of the current record
+	quoted    bool // inside a quoted field
+	leading   bool // at the start of the field
+	commaPos  int  // number of bytes of the delimiter matched
+	lineStart int  // number of bytes of the comment character matched, -1 if no match
+	comments  bool // inside a comment line
+	line      int  // current line
+	start     int  // line of the current record
+	err       error
+}
+
+func newByteLimitReader(r io.Reader, limits Limits, dialect Dialect) *byteLimitReader {
+	b := &byteLimitReader{
+		r:       r,
+		limits:  limits,
+		comma:   []byte(string(dialect.Comma)),
+		trim:    dialect.TrimLeadingSpace,
+		line:    1,
+		start:   1,
+		columns: 1,
+		leading: true,
+	}
+	if dialect.Comment != 0 {
+		b.comment = []byte(string(dialect.Comment))
+	}
+	return b
+}
+
+func (b *byteLimitReader) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.r.Read(p)
+	for i, c := range p[:n] {
+		if limit, max := b.scan(c); limit != "" {
+			// deliver the preceding records before failing
+			b.err = &LimitError{Limit: limit, Max: max, Line: b.start}
+			return i, b.err
+		}
+	}
+
+	return n, err
+}
+
+// scan advances the state by one byte and returns the limit that is exceeded, if any.
+func (b *byteLimitReader) scan(c byte) (limit string, max int) {
+	// line terminators are not part of the record unless they are quoted
+	if terminator := c == '\n' || c == '\r'; !terminator || b.quoted {
+		if b.n++; b.limits.MaxRecordBytes > 0 && b.n > b.limits.MaxRecordBytes {
+			return "record bytes", b.limits.MaxRecordBytes
+		}
+	}
+
+	// detect comment lines, which are skipped by the Reader
+	if b.lineStart >= 0 && b.comment != nil && !b.comments {
+		if c == b.comment[b.lineStart] {
+			if b.lineStart++; b.lineStart == len(b.comment) {
+				b.comments, b.lineStart = true, -1
+			}
+		} else {
+			b.lineStart = -1
+		}
+	}
+
+	switch {
+	case c == '\n':
+		b.line++
+		if !b.quoted || b.comments {
+			b.newRecord()
+			return
+		}
+	case b.comments:
+		return
+	case c == '"':
+		b.quoted = !b.quoted
+		b.leading = false
+		return
+	case !b.quoted && b.isComma(c):
+		b.field, b.leading = 0, true
+		if b.columns++; b.limits.MaxColumns > 0 && b.columns > b.limits.MaxColumns {
+			return "columns", b.limits.MaxColumns
+		}
+		return
+	case c == '\r':
+		return
+	case b.leading && b.trim && (c == ' ' || c == '\t' || c == '\v' || c == '\f'):
+		return
+	}
+
+	// the first bytes of a multibyte delimiter are not counted
+	if !b.quoted && b.commaPos > 0 {
+		return
+	}
+
+	b.leading = false
+	if b.field++; b.limits.MaxFieldBytes > 0 && b.field > b.limits.MaxFieldBytes {
+		return "field bytes", b.limits.MaxFieldBytes
+	}
+	return
+}
+
+// isComma reports whether c completes the delimiter.
+func (b *byteLimitReader) isComma(c byte) bool {
+	if c == b.comma[b.commaPos] {
+		if b.commaPos++; b.commaPos == len(b.comma) {
+			b.commaPos = 0
+			return true
+		}
+		return false
+	}
+	b.commaPos = 0
+	if c == b.comma[0] {
+		b.commaPos = 1
+		return len(b.comma) == 1
+	}
+	return false
+}
+
+func (b *byteLimitReader) newRecord() {
+	b.n, b.field, b.columns, b.start = 0, 0, 1, b.line
+	b.quoted, b.leading, b.comments = false, true, false
+	b.commaPos, b.lineStart = 0, 0
+}
+
+// newReader returns a Reader that reads from r and enforces the limits.
+func (d *Decoder) newReader(r io.Reader) Reader {
+	l := d.limits
+	if (l.MaxRecordBytes > 0 || l.MaxFieldBytes > 0 || l.MaxColumns > 0) && !d.customReader {
+		r = newByteLimitReader(r, l, d.dialect)
+	}
+
+	rd := d.readerFunc(r)
+
+	if l.MaxColumns > 0 || l.MaxFieldBytes > 0 || l.MaxRecordBytes > 0 {
+		rd = &limitReader{Reader: rd, limits: l}
+	}
+
+	return rd
+}
+
+// countRow counts a row against the MaxRows limit.
+func (s *decodeState) countRow() error {
+	if *s.rows++; *s.rows > int64(s.limits.MaxRows) {
+		line, _ := fieldPos(s.r, 0)
+		return &LimitError{Limit: "rows", Max: s.limits.MaxRows, Line: line}
+	}
+	return nil
+}
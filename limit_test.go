@@ -0,0 +1,143 @@
+package csvbuddy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	for _, test := range []struct {
+		Limits Limits
+		Data   string
+		Err    *LimitError
+	}{
+		{Limits{MaxRows: 2}, "start,end\n1,2\n3,4\n", nil},
+		{Limits{MaxRows: 2}, "start,end\n1,2\n3,4\n5,6\n", &LimitError{"rows", 2, 4}},
+		{Limits{MaxColumns: 2}, "start,end\n1,2,3\n", &LimitError{"columns", 2, 2}},
+		{Limits{MaxFieldBytes: 5}, "start,end\n1,2\n123456,2\n", &LimitError{"field bytes", 5, 3}},
+		{Limits{MaxRecordBytes: 10}, "start,end\n1,2\n\"1\n\",123456\n", &LimitError{"record bytes", 10, 3}},
+		{Limits{MaxRecordBytes: 10}, "start,end\n\"1\n2\",3\n", nil},
+		{Limits{MaxRecordBytes: 9}, "start,end\n123,45678\n", nil},
+		{Limits{MaxRecordBytes: 9}, "start,end\r\n123,45678\r\n", nil},
+		{Limits{MaxRecordBytes: 9}, "start,end\n123,45678", nil},
+		{Limits{MaxRecordBytes: 9}, "start,end\n123,456789\n", &LimitError{"record bytes", 9, 2}},
+	} {
+		var data []struct {
+			Start string `csv:"start"`
+			End   string `csv:"end"`
+		}
+		d := NewDecoder(strings.NewReader(test.Data))
+		d.SetLimits(test.Limits)
+
+		var lerr *LimitError
+		err := d.Decode(&data)
+		if test.Err == nil && err != nil {
+			t.Fatal(test.Data, err)
+		} else if test.Err != nil && (!errors.As(err, &lerr) || *lerr != *test.Err) {
+			t.Fatal(test.Data, err)
+		}
+	}
+}
+
+// endlessReader returns prefix followed by an endless repetition of fill.
+type endlessReader struct {
+	prefix string
+	fill   byte
+	n      int // number of bytes read
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	n := copy(p, r.prefix)
+	r.prefix = r.prefix[n:]
+	for i := n; i < len(p); i++ {
+		p[i] = r.fill
+	}
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestLimitsStreaming(t *testing.T) {
+	for _, test := range []struct {
+		Limits Limits
+		Reader *endlessReader
+		Err    LimitError
+	}{
+		{Limits{MaxFieldBytes: 1024}, &endlessReader{prefix: "start,end\n1,", fill: 'x'}, LimitError{"field bytes", 1024, 2}},
+		{Limits{MaxFieldBytes: 1024}, &endlessReader{prefix: "start,end\n1,\"", fill: 'x'}, LimitError{"field bytes", 1024, 2}},
+		{Limits{MaxColumns: 100}, &endlessReader{prefix: "start,end\n1,2\n", fill: ','}, LimitError{"columns", 100, 3}},
+	} {
+		var data []testPeriod
+		d := NewDecoder(test.Reader)
+		d.SetLimits(test.Limits)
+
+		var lerr *LimitError
+		if err := d.Decode(&data); !errors.As(err, &lerr) || *lerr != test.Err {
+			t.Fatal("expected LimitError", err)
+		} else if test.Reader.n > 1<<20 {
+			t.Fatal("read too much", test.Reader.n)
+		}
+	}
+}
+
+func TestLimitsStreamingExact(t *testing.T) {
+	dialect := Dialect{Comma: '€', Comment: '#', TrimLeadingSpace: true}
+	testdata := "# a€b€c€d\nstart€end\n\"1\"\"4\"€   12345\r\n"
+
+	var data []struct {
+		Start string `csv:"start"`
+		End   string `csv:"end"`
+	}
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SetDialect(dialect)
+	d.SetLimits(Limits{MaxFieldBytes: 5, MaxColumns: 2})
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 1 || data[0].Start != `1"4` || data[0].End != "12345" {
+		t.Fatal(data)
+	}
+}
+
+func TestLimitsCustomReader(t *testing.T) {
+	var data []testPeriod
+	d := NewDecoder(strings.NewReader("start,end\n1,2\n3,45678901\n"))
+	d.SetReaderFunc(NewReader)
+	d.SetLimits(Limits{MaxRecordBytes: 8})
+
+	var lerr *LimitError
+	if err := d.Decode(&data); !errors.As(err, &lerr) || *lerr != (LimitError{"record bytes", 8, 3}) {
+		t.Fatal("expected LimitError", err)
+	}
+}
+
+func TestLimitsDecodeFileParallel(t *testing.T) {
+	testdata := parallelData(20000)
+
+	var data []parallelRow
+	f := strings.NewReader(testdata)
+	d := NewDecoder(nil)
+	d.SetConcurrency(4)
+	d.SetLimits(Limits{MaxRows: 19999})
+
+	var lerr *LimitError
+	if err := d.DecodeFileParallel(f, f.Size(), &data); !errors.As(err, &lerr) || *lerr != (LimitError{"rows", 19999, 20001}) {
+		t.Fatal("expected LimitError on line 20001", err)
+	}
+
+	// the limit is crossed in a later chunk than the first one
+	d.SetLimits(Limits{MaxRows: 15000})
+	if err := d.DecodeFileParallel(f, f.Size(), &data); !errors.As(err, &lerr) || *lerr != (LimitError{"rows", 15000, 15002}) {
+		t.Fatal("expected LimitError on line 15002", err)
+	}
+
+	d.SetLimits(Limits{MaxRows: 20000})
+	if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil || len(data) != 20000 {
+		t.Fatal(err, len(data))
+	}
+
+	f = strings.NewReader(testdata + "1,2,\"" + strings.Repeat("x", 100) + "\"\n")
+	d.SetLimits(Limits{MaxRecordBytes: 64})
+	if err := d.DecodeFileParallel(f, f.Size(), &data); !errors.As(err, &lerr) || lerr.Line != 20002 {
+		t.Fatal("expected LimitError on line 20002", err)
+	}
+}
@@ -0,0 +1,129 @@
+package csvbuddy
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is a snapshot of the work done by a Decoder or Encoder.
+type Progress struct {
+	Rows     int64         // rows decoded or encoded successfully
+	Rejected int64         // rows that failed to decode or encode
+	Bytes    int64         // bytes read from the input stream or written to the output stream
+	Elapsed  time.Duration // time since decoding or encoding started
+	Done     bool          // decoding or encoding has ended
+}
+
+// Observer is notified of the progress of a Decoder or Encoder.
+type Observer interface {
+	// ObserveProgress is called at every interval of rows and once more at the end.
+	// Calls are never concurrent, but they may be made from different goroutines
+	// if concurrency is enabled.
+	ObserveProgress(p Progress)
+}
+
+// observation tracks the progress of a single Decode, Iterate or Encode call.
+// The methods of a nil observation do nothing.
+type observation struct {
+	observer Observer
+	interval int64
+	start    time.Time
+	bytes    int64 // accessed atomically because readers may run concurrently
+	mu       sync.Mutex
+	progress Progress
+}
+
+func newObservation(o Observer, interval int) *observation {
+	if o == nil {
+		return nil
+	}
+	return &observation{
+		observer: o,
+		interval: int64(interval),
+		start:    time.Now(),
+	}
+}
+
+// row counts a row and notifies the observer at every interval.
+func (o *observation) row(rejected bool) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if rejected {
+		o.progress.Rejected++
+	} else {
+		o.progress.Rows++
+	}
+
+	if o.interval > 0 && (o.progress.Rows+o.progress.Rejected)%o.interval == 0 {
+		o.notify()
+	}
+}
+
+// done notifies the observer that the work has ended. It is safe to call more than once.
+func (o *observation) done() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.progress.Done {
+		o.progress.Done = true
+		o.notify()
+	}
+}
+
+func (o *observation) notify() {
+	p := o.progress
+	p.Bytes = atomic.LoadInt64(&o.bytes)
+	p.Elapsed = time.Since(o.start)
+	o.observer.ObserveProgress(p)
+}
+
+// reader returns r wrapped to count the bytes read.
+func (o *observation) reader(r io.Reader) io.Reader {
+	if o == nil {
+		return r
+	}
+	return &countingReader{r: r, n: &o.bytes}
+}
+
+// writer returns w wrapped to count the bytes written.
+func (o *observation) writer(w io.Writer) io.Writer {
+	if o == nil {
+		return w
+	}
+	return &countingWriter{w: w, n: &o.bytes}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,121 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testObserver []Progress
+
+func (o *testObserver) ObserveProgress(p Progress) {
+	*o = append(*o, p)
+}
+
+func TestDecoderObserver(t *testing.T) {
+	testdata := parallelData(1000, 999)
+
+	for _, n := range []int{0, 3} {
+		var o testObserver
+		var data []parallelRow
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(n)
+		d.SetObserver(&o, 100)
+		if err := d.Decode(&data); err == nil {
+			t.Fatal("expected error")
+		}
+
+		last := o[len(o)-1]
+		if len(o) < 10 || o[0].Rows != 100 || o[0].Done {
+			t.Fatal("expected progress every 100 rows", o)
+		} else if !last.Done || last.Rows != 999 || last.Rejected != 1 {
+			t.Fatal("wrong final progress", last)
+		} else if last.Bytes != int64(len(testdata)) {
+			t.Fatal("wrong number of bytes", last.Bytes)
+		}
+	}
+}
+
+// lateObserver is a slow observer that counts the notifications that follow the final one.
+type lateObserver struct {
+	done bool
+	late int
+}
+
+func (o *lateObserver) ObserveProgress(p Progress) {
+	if o.done {
+		o.late++
+	} else {
+		time.Sleep(time.Microsecond)
+	}
+	o.done = p.Done
+}
+
+func TestDecoderObserverConcurrencyError(t *testing.T) {
+	var o lateObserver
+	var data []parallelRow
+	d := NewDecoder(strings.NewReader(parallelData(10000, 10)))
+	d.SetConcurrency(8)
+	d.SetObserver(&o, 1)
+	if err := d.Decode(&data); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// give stray workers the chance to notify the observer
+	time.Sleep(10 * time.Millisecond)
+
+	if !o.done || o.late != 0 {
+		t.Fatal("observer notified after Decode returned", o.late)
+	}
+}
+
+func TestDecoderIterateObserver(t *testing.T) {
+	var o testObserver
+	var row parallelRow
+	d := NewDecoder(strings.NewReader(parallelData(10)))
+	d.SetObserver(&o, 0)
+
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for iter.Scan() {
+	}
+
+	iter.Close()
+
+	if len(o) != 1 || !o[0].Done || o[0].Rows != 10 {
+		t.Fatal("expected a single final progress", o)
+	}
+}
+
+func TestEncoderObserver(t *testing.T) {
+	data := []testPeriod{{1, 2}, {3, 4}, {5, 6}}
+
+	var o testObserver
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetObserver(&o, 2)
+	if err := e.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(o) != 2 || o[0].Rows != 2 || !o[1].Done || o[1].Rows != 3 || o[1].Bytes != int64(b.Len()) {
+		t.Fatal("wrong progress", o)
+	}
+
+	o = nil
+	for i := range data {
+		if err := e.EncodeRow(&data[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(o) != 2 || !o[1].Done || o[1].Rows != 3 {
+		t.Fatal("wrong progress", o)
+	}
+}
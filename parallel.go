@@ -0,0 +1,218 @@
+package csvbuddy
+
+import (
+	"io"
+	"reflect"
+	"sync"
+)
+
+// pipelineBatchSize is the number of records converted by a worker at a time.
+const pipelineBatchSize = 128
+
+// recordPos holds the positions of the fields of a record
+// after the Reader has moved on to the next records.
+type recordPos struct {
+	line   int
+	fields []fieldPosition
+	end    recordEnd
+}
+
+// fieldPosition is the line and column of the start of a field.
+type fieldPosition struct {
+	line, column int
+}
+
+func (p *recordPos) FieldPos(field int) (line, column int) {
+	if field < len(p.fields) {
+		return p.fields[field].line, p.fields[field].column
+	}
+	return p.line, 0
+}
+
+// batch is a sequence of consecutive records.
+type batch struct {
+	seq     int
+	records [][]string
+	pos     []recordPos
+	rows    reflect.Value // []T holding the converted records
+	n       int           // number of rows converted without error
+	err     error         // error that stopped the batch after n rows, io.EOF at the end of input
+}
+
+// pipeline reads records on one goroutine, converts them on multiple
+// worker goroutines and delivers the batches in the order they were read.
+type pipeline struct {
+	state   *decodeState
+	batches chan *batch   // read but not yet converted
+	results chan *batch   // converted but possibly out of order
+	tokens  chan struct{} // limits the number of batches in flight
+	done    chan struct{}
+	once    sync.Once
+	workers sync.WaitGroup
+	pending map[int]*batch
+	next    int
+}
+
+func newPipeline(s *decodeState, workers int) *pipeline {
+	p := &pipeline{
+		state:   s,
+		batches: make(chan *batch, workers),
+		results: make(chan *batch, workers),
+		tokens:  make(chan struct{}, 2*workers),
+		done:    make(chan struct{}),
+		pending: map[int]*batch{},
+	}
+
+	// the workers copy the state before the reader starts modifying it
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.convert(*s)
+	}
+
+	go p.read()
+
+	return p
+}
+
+func (p *pipeline) read() {
+	defer close(p.batches)
+
+	for seq := 0; ; seq++ {
+		select {
+		case p.tokens <- struct{}{}:
+		case <-p.done:
+			return
+		}
+
+		b := batch{seq: seq}
+
+		// copy the records into flat buffers because the Reader may reuse them
+		var fields []string
+		var positions []fieldPosition
+		var ends []int
+		for len(ends) < pipelineBatchSize {
+			record, err := p.state.read()
+			if err != nil {
+				b.err = err
+				break
+			}
+
+			// fields may start on later lines than the record if a quoted field spans lines
+			line, _ := fieldPos(p.state.r, 0)
+			for i := range record {
+				line, column := fieldPos(p.state.r, i)
+				positions = append(positions, fieldPosition{line, column})
+			}
+
+			fields = append(fields, record...)
+			ends = append(ends, len(fields))
+			b.pos = append(b.pos, recordPos{line: line, end: p.state.end})
+		}
+
+		start := 0
+		b.records = make([][]string, len(ends))
+		for i, end := range ends {
+			b.records[i] = fields[start:end:end]
+			b.pos[i].fields = positions[start:end:end]
+			start = end
+		}
+
+		last := b.err != nil
+
+		select {
+		case p.batches <- &b:
+		case <-p.done:
+			return
+		}
+
+		if last {
+			return
+		}
+	}
+}
+
+func (p *pipeline) convert(s decodeState) {
+	defer p.workers.Done()
+
+	// the arena is not safe for concurrent use
+	if s.arena != nil {
+		s.arena = &arena{size: s.arena.size}
+	}
+
+	sliceType := reflect.SliceOf(s.structType)
+
+	for {
+		var b *batch
+		select {
+		case b = <-p.batches:
+			if b == nil {
+				return
+			}
+		case <-p.done:
+			return
+		}
+
+		b.rows = reflect.MakeSlice(sliceType, len(b.records), len(b.records))
+		for ; b.n < len(b.records); b.n++ {
+			// stop converting as soon as the pipeline is closed
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+
+			err := s.convert(b.rows.Index(b.n), b.records[b.n], &b.pos[b.n])
+			if s.obs.row(err != nil); err != nil {
+				b.err = err
+				break
+			}
+		}
+
+		select {
+		case p.results <- b:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// nextBatch returns the next batch in input order.
+func (p *pipeline) nextBatch() *batch {
+	for {
+		if b, ok := p.pending[p.next]; ok {
+			delete(p.pending, p.next)
+			p.next++
+			<-p.tokens
+			return b
+		}
+		b := <-p.results
+		p.pending[b.seq] = b
+	}
+}
+
+// close stops the goroutines and waits for the workers to return,
+// so that the observer is not notified afterwards. It is safe to call more than once.
+func (p *pipeline) close() {
+	p.once.Do(func() { close(p.done) })
+	p.workers.Wait()
+}
+
+// decodeAll decodes all batches and appends them to slice.
+func (p *pipeline) decodeAll(slice reflect.Value) (reflect.Value, error) {
+	defer p.close()
+	for {
+		b := p.nextBatch()
+		if slice.Type().Elem().Kind() == reflect.Ptr {
+			for i := 0; i < b.n; i++ {
+				slice = reflect.Append(slice, b.rows.Index(i).Addr())
+			}
+		} else {
+			slice = reflect.AppendSlice(slice, b.rows.Slice(0, b.n))
+		}
+		if b.err == io.EOF {
+			return slice, nil
+		} else if b.err != nil {
+			return slice, b.err
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package csvbuddy
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type parallelRow struct {
+	I int     `csv:"i"`
+	F float64 `csv:"f"`
+	S string  `csv:"s"`
+}
+
+func parallelData(n int, bad ...int) string {
+	var sb strings.Builder
+	sb.WriteString("i,f,s\n")
+	for i := 0; i < n; i++ {
+		f := fmt.Sprint(float64(i) / 2)
+		for _, b := range bad {
+			if i == b {
+				f = "x"
+			}
+		}
+		fmt.Fprintf(&sb, "%d,%s,row %d\n", i, f, i)
+	}
+	return sb.String()
+}
+
+func TestDecodeConcurrency(t *testing.T) {
+	testdata := parallelData(1000)
+
+	var expect []parallelRow
+	if err := Unmarshal([]byte(testdata), &expect); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{2, 4, 7} {
+		var data []parallelRow
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(n)
+		d.SetArenaSize(64)
+		if err := d.Decode(&data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, expect) {
+			t.Fatal("not equal", n)
+		}
+	}
+}
+
+func TestDecodeConcurrencyFirstError(t *testing.T) {
+	testdata := parallelData(1000, 900, 300, 700)
+
+	for i := 0; i < 10; i++ {
+		var data []parallelRow
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(4)
+
+		var pe *csv.ParseError
+		if err := d.Decode(&data); !errors.As(err, &pe) {
+			t.Fatal("expected ParseError", err)
+		} else if pe.Line != 302 || pe.Column != 5 {
+			t.Fatal("expected first error on line 302", err)
+		} else if data != nil {
+			t.Fatal("should not be assigned on error")
+		}
+	}
+}
+
+func TestDecodeConcurrencyMultilineError(t *testing.T) {
+	// the failing field starts on the line following the start of the record
+	var sb strings.Builder
+	sb.WriteString("s,i,f\n")
+	for i := 0; i < 1000; i++ {
+		f := fmt.Sprint(i)
+		if i == 700 {
+			f = "x"
+		}
+		fmt.Fprintf(&sb, "\"row\n%d\",%d,%s\n", i, i, f)
+	}
+
+	for _, testdata := range []string{sb.String(), bom + strings.ReplaceAll(sb.String(), "\n", "\r\n")} {
+		var data []parallelRow
+		expect := Unmarshal([]byte(testdata), &data)
+		if expect == nil {
+			t.Fatal("expected error")
+		}
+
+		d := NewDecoder(strings.NewReader(testdata))
+		d.SetConcurrency(4)
+		if err := d.Decode(&data); err == nil || err.Error() != expect.Error() {
+			t.Fatal("expected", expect, "got", err)
+		}
+	}
+}
+
+func TestDecoderIterateConcurrency(t *testing.T) {
+	testdata := parallelData(500, 400)
+
+	var row parallelRow
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SetConcurrency(3)
+
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for ; iter.Scan(); n++ {
+		if row.I != n || row.S != fmt.Sprintf("row %d", n) {
+			t.Fatal("out of order", n, row)
+		}
+	}
+
+	var pe *csv.ParseError
+	if n != 400 {
+		t.Fatal("expected 400 rows", n)
+	} else if err := iter.Err(); !errors.As(err, &pe) || pe.Line != 402 {
+		t.Fatal("expected error on line 402", err)
+	}
+}
+
+func TestDecoderIterateConcurrencyClose(t *testing.T) {
+	var row parallelRow
+	d := NewDecoder(strings.NewReader(parallelData(5000)))
+	d.SetConcurrency(2)
+
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !iter.Scan() || row.I != 0 {
+		t.Fatal("expected first row", row)
+	}
+
+	iter.Close()
+}
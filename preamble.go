@@ -0,0 +1,122 @@
+package csvbuddy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// SkipLines causes the Decoder to skip the first n lines of the input stream
+// before reading the header, such as the title of a report.
+// The skipped lines are returned by Preamble.
+func (d *Decoder) SkipLines(n int) { d.skipLines = n }
+
+// SkipUntil causes the Decoder to skip lines until fn reports true for a line,
+// which is then read as the header. The lines are passed to fn without line terminator.
+// It is applied after SkipLines. The skipped lines are returned by Preamble.
+func (d *Decoder) SkipUntil(fn func(line string) bool) { d.skipUntil = fn }
+
+// Preamble returns the lines that were skipped before the header
+// by the last call to Decode, Iterate or DecodeFileParallel.
+func (d *Decoder) Preamble() []string { return d.preamble }
+
+// readPreamble skips the lines preceding the header in r.
+// It returns a reader of the remaining input and the number of bytes skipped.
+func (d *Decoder) readPreamble(r io.Reader) (io.Reader, int64, error) {
+	d.preamble = nil
+	if d.skipLines <= 0 && d.skipUntil == nil {
+		return r, 0, nil
+	}
+
+	br := bufio.NewReader(r)
+	var size int64
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		} else if line == "" {
+			return br, size, nil
+		}
+
+		text := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if size == 0 {
+			text = strings.TrimPrefix(text, bom)
+		}
+
+		if len(d.preamble) >= d.skipLines && (d.skipUntil == nil || d.skipUntil(text)) {
+			// give the header back
+			return io.MultiReader(strings.NewReader(line), br), size, nil
+		}
+
+		d.preamble = append(d.preamble, text)
+		size += int64(len(line))
+	}
+}
+
+// skipLinesAt returns the offset following the first n lines of f.
+func skipLinesAt(f io.ReaderAt, size int64, n int) (off int64, err error) {
+	br := bufio.NewReader(io.NewSectionReader(f, 0, size))
+	for ; n > 0; n-- {
+		line, err := br.ReadSlice('\n')
+		for err == bufio.ErrBufferFull {
+			off += int64(len(line))
+			line, err = br.ReadSlice('\n')
+		}
+		if off += int64(len(line)); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err
+		}
+	}
+	return off, nil
+}
+
+// SetPreamble causes the Encoder to write lines, such as a title or comments,
+// before the header. The lines are written as is and terminated by \r\n
+// if the Encoder uses a Dialect with UseCRLF and by \n otherwise.
+func (e *Encoder) SetPreamble(lines ...string) { e.preamble = lines }
+
+// preambleWriter writes the preamble before the first write to w,
+// but after a byte order mark.
+type preambleWriter struct {
+	w       io.Writer
+	lines   []string
+	newline string
+	written bool
+}
+
+func (p *preambleWriter) Write(b []byte) (int, error) {
+	if !p.written {
+		n := 0
+		if bytes.HasPrefix(b, []byte(bom)) {
+			n = len(bom)
+		}
+		if _, err := p.w.Write(b[:n]); err != nil {
+			return 0, err
+		} else if err := p.flush(); err != nil {
+			return 0, err
+		} else if _, err := p.w.Write(b[n:]); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return p.w.Write(b)
+}
+
+// flush writes the preamble if it has not been written yet.
+func (p *preambleWriter) flush() error {
+	if p.written {
+		return nil
+	}
+	p.written = true
+
+	var b strings.Builder
+	for _, line := range p.lines {
+		b.WriteString(line)
+		b.WriteString(p.newline)
+	}
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
@@ -0,0 +1,114 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeSkipLines(t *testing.T) {
+	testdata := "Sales report\n\nstart,end\n1,2\n3,x\n"
+
+	var data []testPeriod
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SkipLines(2)
+
+	var pe *csv.ParseError
+	if err := d.Decode(&data); !errors.As(err, &pe) || pe.Line != 5 || pe.Column != 3 {
+		t.Fatal("expected error on line 5", err)
+	} else if !reflect.DeepEqual(d.Preamble(), []string{"Sales report", ""}) {
+		t.Fatalf("%q", d.Preamble())
+	}
+}
+
+func TestDecodeSkipUntil(t *testing.T) {
+	testdata := bom + "Sales report\r\n\"quoted, title\"\r\n\r\nstart,end\r\n1,2\r\n3,4\r\n"
+	isHeader := func(line string) bool { return strings.HasPrefix(line, "start,") }
+
+	var data []testPeriod
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SkipUntil(isHeader)
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) {
+		t.Fatal("not equal", data)
+	} else if !reflect.DeepEqual(d.Preamble(), []string{"Sales report", `"quoted, title"`, ""}) {
+		t.Fatalf("%q", d.Preamble())
+	}
+
+	// the header is the first line
+	d = NewDecoder(strings.NewReader(bom + "start,end\n5,6\n"))
+	d.SkipUntil(isHeader)
+	if err := d.Decode(&data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{5, 6}}) || len(d.Preamble()) != 0 {
+		t.Fatal("not equal", data, d.Preamble())
+	}
+
+	// parallel decoding
+	f := strings.NewReader(testdata)
+	d = NewDecoder(nil)
+	d.SkipUntil(isHeader)
+	if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) || len(d.Preamble()) != 3 {
+		t.Fatal("not equal", data, d.Preamble())
+	}
+}
+
+func TestDecoderIterateSkipLinesCheckpoint(t *testing.T) {
+	testdata := "Title\nstart,end\n1,2\n3,4\n"
+
+	var row testPeriod
+	d := NewDecoder(strings.NewReader(testdata))
+	d.SkipLines(1)
+	iter, err := d.Iterate(&row)
+	if err != nil {
+		t.Fatal(err)
+	} else if !iter.Scan() {
+		t.Fatal(iter.Err())
+	} else if cp := iter.Checkpoint(); iter.Line() != 3 || cp.Offset != 20 || cp.Line != 4 {
+		t.Fatal("wrong checkpoint", iter.Line(), cp)
+	}
+
+	// without header
+	d = NewDecoder(strings.NewReader("Title\n1,2\n"))
+	d.SkipLines(1)
+	d.SkipHeader()
+	if iter, err = d.Iterate(&row); err != nil {
+		t.Fatal(err)
+	} else if cp := iter.Checkpoint(); cp.Offset != 6 || cp.Line != 2 {
+		t.Fatal("wrong checkpoint", cp)
+	}
+}
+
+func TestEncodePreamble(t *testing.T) {
+	for _, test := range []struct {
+		Dialect    *Dialect
+		SkipHeader bool
+		Data       []testPeriod
+		Text       string
+	}{
+		{nil, false, []testPeriod{{1, 2}}, "Sales report\n\nstart,end\n1,2\n"},
+		{&DialectExcel, false, []testPeriod{{1, 2}}, bom + "Sales report\r\n\r\nstart,end\r\n1,2\r\n"},
+		{nil, true, nil, "Sales report\n\n"},
+	} {
+		var b bytes.Buffer
+		e := NewEncoder(&b)
+		if test.Dialect != nil {
+			e.SetDialect(*test.Dialect)
+		}
+		if test.SkipHeader {
+			e.SkipHeader()
+		}
+		e.SetPreamble("Sales report", "")
+		if err := e.Encode(&test.Data); err != nil {
+			t.Fatal(err)
+		} else if b.String() != test.Text {
+			t.Fatalf("%q", b.String())
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package csvbuddy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrEscape signals that a field must be escaped but the Dialect has no Escape character.
+var ErrEscape = errors.New("field must be escaped but there is no escape character")
+
+// Quoting is the policy that decides which fields are quoted when encoding.
+type Quoting int
+
+const (
+	// QuoteMinimal quotes fields that contain the delimiter, quotes or line breaks
+	// or begin with white space, like csv.Writer. This is the default.
+	QuoteMinimal Quoting = iota
+
+	// QuoteAll quotes all fields.
+	QuoteAll
+
+	// QuoteNonNumeric quotes all fields except decimal numbers.
+	QuoteNonNumeric
+
+	// QuoteNone never quotes fields. Delimiters, quotes, line breaks
+	// and the escape character itself are preceded by the Escape character.
+	QuoteNone
+)
+
+// quotingWriter is a Writer that quotes fields according to a Quoting policy.
+type quotingWriter struct {
+	w       *bufio.Writer
+	comma   rune
+	useCRLF bool
+	quoting Quoting
+	escape  rune
+}
+
+func newQuotingWriter(w io.Writer, d Dialect) *quotingWriter {
+	return &quotingWriter{
+		w:       bufio.NewWriter(w),
+		comma:   d.Comma,
+		useCRLF: d.UseCRLF,
+		quoting: d.Quoting,
+		escape:  d.Escape,
+	}
+}
+
+func (w *quotingWriter) Write(record []string) (err error) {
+	if !validDelim(w.comma) || (w.escape != 0 && (!validDelim(w.escape) || w.escape == w.comma)) {
+		return errInvalidDelim
+	}
+
+	if w.quoting == QuoteNone && w.escape == 0 {
+		for _, field := range record {
+			if strings.ContainsRune(field, w.comma) || strings.ContainsAny(field, "\"\r\n") {
+				return fmt.Errorf("%w: %q", ErrEscape, field)
+			}
+		}
+	}
+
+	for n, field := range record {
+		if n > 0 {
+			w.w.WriteRune(w.comma)
+		}
+
+		switch {
+		case w.quoting == QuoteNone:
+			w.writeEscaped(field)
+		case w.quoting == QuoteAll,
+			w.quoting == QuoteNonNumeric && !isDecimal(field),
+			w.fieldNeedsQuotes(field):
+			w.writeQuoted(field)
+		default:
+			w.w.WriteString(field)
+		}
+	}
+
+	if w.useCRLF {
+		_, err = w.w.WriteString("\r\n")
+	} else {
+		err = w.w.WriteByte('\n')
+	}
+	return err
+}
+
+// writeQuoted writes field in quotes like csv.Writer.
+func (w *quotingWriter) writeQuoted(field string) {
+	w.w.WriteByte('"')
+	for len(field) > 0 {
+		i := strings.IndexAny(field, "\"\r\n")
+		if i < 0 {
+			i = len(field)
+		}
+
+		w.w.WriteString(field[:i])
+		field = field[i:]
+
+		if len(field) > 0 {
+			switch field[0] {
+			case '"':
+				w.w.WriteString(`""`)
+			case '\r':
+				if !w.useCRLF {
+					w.w.WriteByte('\r')
+				}
+			case '\n':
+				if w.useCRLF {
+					w.w.WriteString("\r\n")
+				} else {
+					w.w.WriteByte('\n')
+				}
+			}
+			field = field[1:]
+		}
+	}
+	w.w.WriteByte('"')
+}
+
+// writeEscaped writes field with special characters preceded by the escape character.
+func (w *quotingWriter) writeEscaped(field string) {
+	for _, r := range field {
+		if w.escape != 0 && (r == w.comma || r == '"' || r == '\r' || r == '\n' || r == w.escape) {
+			w.w.WriteRune(w.escape)
+		}
+		w.w.WriteRune(r)
+	}
+}
+
+func (w *quotingWriter) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	} else if field == `\.` {
+		return true
+	} else if strings.ContainsRune(field, w.comma) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *quotingWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// isDecimal reports whether s is a decimal number with optional sign, fraction and exponent.
+func isDecimal(s string) bool {
+	digits := func() (n int) {
+		for ; len(s) > 0 && s[0] >= '0' && s[0] <= '9'; s = s[1:] {
+			n++
+		}
+		return
+	}
+
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+
+	n := digits()
+	if len(s) > 0 && s[0] == '.' {
+		s = s[1:]
+		n += digits()
+	}
+	if n == 0 {
+		return false
+	}
+
+	if len(s) > 0 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+
+	return s == ""
+}
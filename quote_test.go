@@ -0,0 +1,108 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuoting(t *testing.T) {
+	record := []string{"a", "", "1", "-2.5e3", "x,y", `say "hi"`, "two\nlines", " lead", `\.`, "1.2.3", "NaN"}
+
+	for _, test := range []struct {
+		Dialect Dialect
+		Text    string
+	}{
+		{
+			Dialect{Comma: ',', Quoting: QuoteAll},
+			`"a","","1","-2.5e3","x,y","say ""hi""","two` + "\n" + `lines"," lead","\.","1.2.3","NaN"` + "\n",
+		},
+		{
+			Dialect{Comma: ',', Quoting: QuoteAll, UseCRLF: true},
+			`"a","","1","-2.5e3","x,y","say ""hi""","two` + "\r\n" + `lines"," lead","\.","1.2.3","NaN"` + "\r\n",
+		},
+		{
+			Dialect{Comma: ',', Quoting: QuoteNonNumeric},
+			`"a","",1,-2.5e3,"x,y","say ""hi""","two` + "\n" + `lines"," lead","\.","1.2.3","NaN"` + "\n",
+		},
+		{
+			Dialect{Comma: ';', Quoting: QuoteNonNumeric},
+			`"a";"";1;-2.5e3;"x,y";"say ""hi""";"two` + "\n" + `lines";" lead";"\.";"1.2.3";"NaN"` + "\n",
+		},
+		{
+			Dialect{Comma: ',', Quoting: QuoteNone, Escape: '\\'},
+			`a,,1,-2.5e3,x\,y,say \"hi\",two\` + "\n" + `lines, lead,\\.,1.2.3,NaN` + "\n",
+		},
+		{
+			Dialect{Comma: '\t', Quoting: QuoteNone, Escape: '\\'},
+			"a\t\t1\t-2.5e3\tx,y\tsay \\\"hi\\\"\ttwo\\\nlines\t lead\t\\\\.\t1.2.3\tNaN\n",
+		},
+	} {
+		var b bytes.Buffer
+		w := test.Dialect.NewWriter(&b)
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		} else if err := w.(interface{ Flush() error }).Flush(); err != nil {
+			t.Fatal(err)
+		} else if b.String() != test.Text {
+			t.Fatalf("%+v: %q", test.Dialect, b.String())
+		}
+
+		// quoted output is read back unchanged
+		if test.Dialect.Quoting != QuoteNone {
+			cr := csv.NewReader(&b)
+			cr.Comma = test.Dialect.Comma
+			if record2, err := cr.Read(); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(record, record2) {
+				t.Fatalf("%+v: %q", test.Dialect, record2)
+			}
+		}
+	}
+}
+
+func TestQuoteNoneWithoutEscape(t *testing.T) {
+	var b bytes.Buffer
+	w := Dialect{Comma: ',', Quoting: QuoteNone}.NewWriter(&b)
+
+	if err := w.Write([]string{"a", `b"c`}); !errors.Is(err, ErrEscape) {
+		t.Fatal(err)
+	} else if err := w.Write([]string{"a", "b c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	w.(interface{ Flush() error }).Flush()
+	if b.String() != "a,b c\n" {
+		t.Fatalf("%q", b.String())
+	}
+
+	if err := (Dialect{Comma: ',', Quoting: QuoteNone, Escape: ','}).NewWriter(&b).Write(nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEncodeQuoting(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.SetDialect(Dialect{Comma: ',', Quoting: QuoteNonNumeric, BOM: true})
+	if err := e.Encode(&[]testPeriod{{1, 2}}); err != nil {
+		t.Fatal(err)
+	} else if b.String() != bom+"\"start\",\"end\"\n1,2\n" {
+		t.Fatalf("%q", b.String())
+	}
+}
+
+func TestIsDecimal(t *testing.T) {
+	for _, s := range []string{"0", "-1", "+1.5", ".5", "5.", "1e10", "1E-3"} {
+		if !isDecimal(s) {
+			t.Error(s)
+		}
+	}
+	for _, s := range []string{"", "-", ".", "e5", "1e", "1.2.3", "0x10", "Inf", "1 "} {
+		if isDecimal(s) {
+			t.Error(s)
+		}
+	}
+}
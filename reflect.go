@@ -24,18 +24,59 @@ type converter interface {
 	Encode(reflect.Value) (string, error)
 }
 
-type boolCodec struct{}
+// BoolFormat defines the strings that represent boolean values.
+// Values are matched case-insensitively when decoding.
+// The first elements of True and False are written when encoding.
+type BoolFormat struct {
+	True  []string // strings decoded as true
+	False []string // strings decoded as false
+}
+
+func (f *BoolFormat) parse(s string) (bool, error) {
+	for _, t := range f.True {
+		if strings.EqualFold(s, t) {
+			return true, nil
+		}
+	}
+	for _, t := range f.False {
+		if strings.EqualFold(s, t) {
+			return false, nil
+		}
+	}
+	return false, &strconv.NumError{Func: "ParseBool", Num: s, Err: strconv.ErrSyntax}
+}
+
+func (f *BoolFormat) format(b bool) string {
+	if b && len(f.True) > 0 {
+		return f.True[0]
+	} else if !b && len(f.False) > 0 {
+		return f.False[0]
+	}
+	return strconv.FormatBool(b)
+}
+
+type boolCodec struct {
+	Format *BoolFormat // nil uses strconv.ParseBool and strconv.FormatBool
+}
 
 func (b *boolCodec) Decode(v reflect.Value, s string) (err error) {
 	var x bool
-	if x, err = strconv.ParseBool(s); err == nil {
+	if b.Format == nil {
+		x, err = strconv.ParseBool(s)
+	} else {
+		x, err = b.Format.parse(s)
+	}
+	if err == nil {
 		v.SetBool(x)
 	}
 	return
 }
 
 func (b *boolCodec) Encode(v reflect.Value) (string, error) {
-	return strconv.FormatBool(v.Bool()), nil
+	if b.Format == nil {
+		return strconv.FormatBool(v.Bool()), nil
+	}
+	return b.Format.format(v.Bool()), nil
 }
 
 type complexCodec struct {
@@ -173,67 +214,108 @@ func (c *ptrCodec) Encode(v reflect.Value) (string, error) {
 	return c.converter.Encode(v)
 }
 
+type fieldCodec struct {
+	Tag      FieldTag
+	fallback converter // used if only one of the interfaces is implemented
+}
+
+func (c *fieldCodec) Decode(v reflect.Value, s string) error {
+	if fu, ok := v.Addr().Interface().(CSVFieldUnmarshaler); ok {
+		return fu.UnmarshalCSVField(c.Tag, s)
+	} else if c.fallback != nil {
+		return c.fallback.Decode(v, s)
+	}
+	return errors.New("value does not implement csvbuddy.CSVFieldUnmarshaler")
+}
+
+func (c *fieldCodec) Encode(v reflect.Value) (string, error) {
+	if fm, ok := v.Addr().Interface().(CSVFieldMarshaler); ok {
+		return fm.MarshalCSVField(c.Tag)
+	} else if c.fallback != nil {
+		return c.fallback.Encode(v)
+	}
+	return "", errors.New("value does not implement csvbuddy.CSVFieldMarshaler")
+}
+
+func implementsFieldMarshaler(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return ptr.Implements(fieldUnmarshalerType) || ptr.Implements(fieldMarshalerType)
+}
+
 func implementsTextMarshaler(t reflect.Type) bool {
 	ptr := reflect.PtrTo(t)
 	return ptr.Implements(textUnmarshalerType) || ptr.Implements(textMarshalerType)
 }
 
-func newValueConverter(t reflect.Type, name string, base, prec int, ffmt byte) (converter, error) {
-	if implementsTextMarshaler(t) {
+func newValueConverter(t reflect.Type, tag FieldTag) (converter, error) {
+	if t.Kind() == reflect.Ptr {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		c, err := newValueConverter(t, tag)
+		if err != nil {
+			return nil, err
+		}
+		return &ptrCodec{c}, nil
+	}
+
+	c, err := newScalarConverter(t, tag)
+	if err != nil {
+		return nil, err
+	} else if tag.OneOf != nil {
+		c = &oneOfCodec{c, tag.Name, tag.OneOf}
+	}
+	return c, nil
+}
+
+func newScalarConverter(t reflect.Type, tag FieldTag) (converter, error) {
+	if implementsFieldMarshaler(t) {
+		var fallback converter
+		if implementsTextMarshaler(t) {
+			fallback = &textCodec{}
+		}
+		return &fieldCodec{tag, fallback}, nil
+	} else if implementsTextMarshaler(t) {
 		return &textCodec{}, nil
+	} else if c := enumCodecOf(t, tag.Name); c != nil {
+		return c, nil
 	}
 
 	switch t.Kind() {
 	case reflect.Bool:
-		return &boolCodec{}, nil
+		return &boolCodec{tag.Bool}, nil
 	case reflect.Complex64, reflect.Complex128:
-		return &complexCodec{t.Bits(), prec, ffmt}, nil
+		return &complexCodec{t.Bits(), tag.Prec, tag.Fmt}, nil
 	case reflect.Float32, reflect.Float64:
-		return &floatCodec{t.Bits(), prec, ffmt}, nil
+		return &floatCodec{t.Bits(), tag.Prec, tag.Fmt}, nil
 	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
-		return &intCodec{t.Bits(), base}, nil
-	case reflect.Ptr:
-		for t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-		if implementsTextMarshaler(t) {
-			return &ptrCodec{&textCodec{}}, nil
-		}
-		switch t.Kind() {
-		case reflect.Bool:
-			return &ptrCodec{&boolCodec{}}, nil
-		case reflect.Complex64, reflect.Complex128:
-			return &ptrCodec{&complexCodec{t.Bits(), prec, ffmt}}, nil
-		case reflect.Float32, reflect.Float64:
-			return &ptrCodec{&floatCodec{t.Bits(), prec, ffmt}}, nil
-		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
-			return &ptrCodec{&intCodec{t.Bits(), base}}, nil
-		case reflect.String:
-			return &ptrCodec{&stringCodec{}}, nil
-		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
-			return &ptrCodec{&uintCodec{t.Bits(), base}}, nil
-		}
-		if t.ConvertibleTo(byteSliceType) {
-			return &ptrCodec{&byteSliceCodec{}}, nil
-		}
-		return nil, fmt.Errorf("cannot decode field '%s'", name)
+		return &intCodec{t.Bits(), tag.Base}, nil
 	case reflect.String:
 		return &stringCodec{}, nil
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
-		return &uintCodec{t.Bits(), base}, nil
+		return &uintCodec{t.Bits(), tag.Base}, nil
 	}
 
 	if t.ConvertibleTo(byteSliceType) {
 		return &byteSliceCodec{}, nil
 	}
 
-	return nil, fmt.Errorf("cannot decode field '%s'", name)
+	return nil, fmt.Errorf("cannot decode field '%s'", tag.Name)
 }
 
 type structField struct {
-	Index     []int  // struct field index
-	Name      string // column name
-	converter        // value converter
+	Index       []int        // struct field index
+	Name        string       // column name
+	converter                // value converter
+	Constraints *constraints // validation rules, nil if none
+	Formula     bool         // values are not escaped by Encoder.SetEscapeFormulas
+}
+
+func (f *structField) validate(v reflect.Value, s string) error {
+	if f.Constraints == nil {
+		return nil
+	}
+	return f.Constraints.check(f.Name, v, s)
 }
 
 func valueOf(i interface{}) (v reflect.Value, err error) {
@@ -245,15 +327,49 @@ func valueOf(i interface{}) (v reflect.Value, err error) {
 	return
 }
 
-func parseTag(tag string) (name string, base, prec int, fmt byte) {
-	base, prec, fmt = 10, -1, 'f'
+// FieldTag holds the parsed options of a csv struct field tag.
+type FieldTag struct {
+	Name    string      // column name
+	Base    int         // integer base
+	Prec    int         // floating point precision
+	Fmt     byte        // floating point format
+	Bool    *BoolFormat // boolean strings
+	OneOf   []string    // allowed values
+	Min     string      // numeric lower bound
+	Max     string      // numeric upper bound
+	MinLen  int         // minimum text length, -1 if unset
+	MaxLen  int         // maximum text length, -1 if unset
+	Pattern string      // regular expression that the text must match
+	Options []string    // all options following the name, as written
+}
+
+// Lookup returns the value of the option key=value, or an empty string
+// if the option is a flag. The return value ok reports whether the option is present.
+func (t FieldTag) Lookup(key string) (value string, ok bool) {
+	for _, opt := range t.Options {
+		if opt == key {
+			return "", true
+		} else if strings.HasPrefix(opt, key) && len(opt) > len(key) && opt[len(key)] == '=' {
+			return opt[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// ParseTag parses the value of a csv struct field tag.
+// It is used by code generated by csvbuddy-gen.
+func ParseTag(tag string) FieldTag { return parseTag(tag) }
+
+func parseTag(tag string) (t FieldTag) {
+	t.Base, t.Prec, t.Fmt = 10, -1, 'f'
+	t.MinLen, t.MaxLen = -1, -1
 	// parse the name
 	i := strings.IndexByte(tag, ',')
 	if i == -1 {
-		name = tag
+		t.Name = tag
 		return
 	}
-	name, tag = tag[:i], tag[i+1:]
+	t.Name, tag = tag[:i], tag[i+1:]
 	// parse the other parameters
 	var val string
 	for tag != "" {
@@ -262,21 +378,45 @@ func parseTag(tag string) (name string, base, prec int, fmt byte) {
 		} else {
 			val, tag = tag[:i], tag[i+1:]
 		}
+		if strings.HasPrefix(val, "pattern=") && tag != "" { // may contain commas
+			val, tag = val+","+tag, ""
+		}
+		t.Options = append(t.Options, val)
 		switch {
 		case strings.HasPrefix(val, "base="): // integer base
 			if n, err := strconv.Atoi(val[5:]); err == nil {
-				base = n
+				t.Base = n
 			}
 		case strings.HasPrefix(val, "prec="): // floating point precision
 			if n, err := strconv.Atoi(val[5:]); err == nil {
-				prec = n
+				t.Prec = n
 			}
 		case strings.HasPrefix(val, "fmt="): // floating point format
 			if len(val) >= 4 {
 				if c := val[4]; strings.IndexByte("beEfgGxX", c) >= 0 {
-					fmt = c
+					t.Fmt = c
 				}
 			}
+		case strings.HasPrefix(val, "bool="): // boolean strings
+			if tf := strings.Split(val[5:], "|"); len(tf) == 2 {
+				t.Bool = &BoolFormat{True: tf[:1], False: tf[1:]}
+			}
+		case strings.HasPrefix(val, "oneof="): // allowed values
+			t.OneOf = strings.Split(val[6:], "|")
+		case strings.HasPrefix(val, "min="): // numeric lower bound
+			t.Min = val[4:]
+		case strings.HasPrefix(val, "max="): // numeric upper bound
+			t.Max = val[4:]
+		case strings.HasPrefix(val, "minlen="): // minimum text length
+			if n, err := strconv.Atoi(val[7:]); err == nil {
+				t.MinLen = n
+			}
+		case strings.HasPrefix(val, "maxlen="): // maximum text length
+			if n, err := strconv.Atoi(val[7:]); err == nil {
+				t.MaxLen = n
+			}
+		case strings.HasPrefix(val, "pattern="): // regular expression
+			t.Pattern = val[8:]
 		}
 	}
 	return
@@ -295,28 +435,29 @@ func appendStructFields(t reflect.Type, index []int, fields *[]structField, name
 				}
 			}
 
-			var name string
-			var base, prec int
-			var ffmt byte
-			base, prec, ffmt = 10, -1, 'f'
-			if tag, ok := field.Tag.Lookup("csv"); ok {
-				name, base, prec, ffmt = parseTag(tag)
+			tag := parseTag(field.Tag.Get("csv"))
+			if tag.Name == "" {
+				tag.Name = field.Name
 			}
-			if name == "" {
-				name = field.Name
-			}
-			if name != "-" {
+			if name := tag.Name; name != "-" {
 				if _, exists := (*names)[name]; exists {
 					return fmt.Errorf("duplicate field name '%s'", name)
 				}
-				codec, err := newValueConverter(field.Type, name, base, prec, ffmt)
+				codec, err := newValueConverter(field.Type, tag)
 				if err != nil {
 					return err
 				}
+				cons, err := newConstraints(field.Type, tag)
+				if err != nil {
+					return err
+				}
+				_, formula := tag.Lookup("formula")
 				*fields = append(*fields, structField{
-					Index:     append(append([]int{}, index...), field.Index...),
-					Name:      name,
-					converter: codec,
+					Index:       append(append([]int{}, index...), field.Index...),
+					Name:        name,
+					converter:   codec,
+					Constraints: cons,
+					Formula:     formula,
 				})
 				(*names)[name] = struct{}{}
 			}
@@ -343,6 +484,52 @@ func structFieldsOf(t reflect.Type) ([]structField, error) {
 	return fields, nil
 }
 
+// withBoolFormat returns a copy of fields in which
+// bool fields without a bool tag option are converted using f.
+func withBoolFormat(fields []structField, f *BoolFormat) []structField {
+	res := make([]structField, len(fields))
+	for i, field := range fields {
+		switch c := field.converter.(type) {
+		case *boolCodec:
+			if c.Format == nil {
+				field.converter = &boolCodec{f}
+			}
+		case *ptrCodec:
+			if b, ok := c.converter.(*boolCodec); ok && b.Format == nil {
+				field.converter = &ptrCodec{&boolCodec{f}}
+			}
+		}
+		res[i] = field
+	}
+	return res
+}
+
+// checkGenerated returns an error if the code generated by csvbuddy-gen
+// for structType would ignore the bool format f or a registered enumeration.
+// Fields that cannot be converted by reflection are left to the generated code.
+func checkGenerated(structType reflect.Type, f *BoolFormat) error {
+	fields, err := structFieldsOf(structType)
+	if err != nil {
+		return nil
+	}
+
+	for _, field := range fields {
+		c := field.converter
+		if p, ok := c.(*ptrCodec); ok {
+			c = p.converter
+		}
+		switch c := c.(type) {
+		case *boolCodec:
+			if f != nil && c.Format == nil {
+				return fmt.Errorf("csv: generated code of field '%s' ignores the BoolFormat; add the bool tag option", field.Name)
+			}
+		case *enumCodec:
+			return fmt.Errorf("csv: generated code of field '%s' ignores the registered enumeration", field.Name)
+		}
+	}
+	return nil
+}
+
 func innerTypeOf(t reflect.Type, kinds ...reflect.Kind) reflect.Type {
 	for i := 0; i < len(kinds)-1; i++ {
 		if t.Kind() != kinds[i] {
@@ -356,6 +543,15 @@ func innerTypeOf(t reflect.Type, kinds ...reflect.Kind) reflect.Type {
 	return t
 }
 
+// sliceStructType returns the struct type of t, which must be a pointer
+// to a slice of structs or a pointer to a slice of pointers to structs.
+func sliceStructType(t reflect.Type) reflect.Type {
+	if structType := innerTypeOf(t, reflect.Ptr, reflect.Slice, reflect.Struct); structType != nil {
+		return structType
+	}
+	return innerTypeOf(t, reflect.Ptr, reflect.Slice, reflect.Ptr, reflect.Struct)
+}
+
 func headerIndices(header []string, fields []structField) (indices []int, err error) {
 	// check for duplicate header names
 	names := make(map[string]struct{}, len(header))
@@ -392,8 +588,9 @@ func headerOf(t reflect.Type) ([]string, error) {
 	return header, nil
 }
 
-// Header returns the header of v, which must be a pointer to a slice of structs.
+// Header returns the header of v, which must be a pointer to a slice of structs
+// or a pointer to a slice of pointers to structs.
 func Header(v interface{}) ([]string, error) {
-	t := innerTypeOf(reflect.TypeOf(v), reflect.Ptr, reflect.Slice, reflect.Struct)
+	t := sliceStructType(reflect.TypeOf(v))
 	return headerOf(t)
 }
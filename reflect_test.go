@@ -1,7 +1,10 @@
 package csvbuddy
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -133,7 +136,7 @@ func TestValueDecoders(t *testing.T) {
 
 	for _, testCase := range testCases {
 		v := reflect.New(testCase.Type)
-		if codec, err := newValueConverter(testCase.Type, "", 10, -1, 'f'); err != nil {
+		if codec, err := newValueConverter(testCase.Type, parseTag("")); err != nil {
 			t.Error(testCase.String, err)
 		} else if err := codec.Decode(v.Elem(), testCase.String); err != nil {
 			t.Error(testCase.String, err)
@@ -179,7 +182,7 @@ func TestValueEncoders(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		if codec, err := newValueConverter(testCase.Type, "", 10, -1, 'f'); err != nil {
+		if codec, err := newValueConverter(testCase.Type, parseTag("")); err != nil {
 			t.Error(testCase.Type, err)
 		} else if val, err := codec.Encode(reflect.ValueOf(testCase.Value)); err != nil {
 			t.Error(testCase.Type, err)
@@ -208,22 +211,74 @@ func TestParseTag(t *testing.T) {
 		{"my_field,prec=5,fmt=G", "my_field", 10, 5, 'G'},
 	}
 	for _, testcase := range testcases {
-		name, base, prec, ffmt := parseTag(testcase.Tag)
-		if name != testcase.Name {
-			t.Error(testcase.Tag, name, "!=", testcase.Name)
+		tag := parseTag(testcase.Tag)
+		if tag.Name != testcase.Name {
+			t.Error(testcase.Tag, tag.Name, "!=", testcase.Name)
 		}
-		if base != testcase.Base {
-			t.Error(testcase.Tag, base, "!=", testcase.Base)
+		if tag.Base != testcase.Base {
+			t.Error(testcase.Tag, tag.Base, "!=", testcase.Base)
 		}
-		if prec != testcase.Prec {
-			t.Error(testcase.Tag, prec, "!=", testcase.Prec)
+		if tag.Prec != testcase.Prec {
+			t.Error(testcase.Tag, tag.Prec, "!=", testcase.Prec)
 		}
-		if ffmt != testcase.Fmt {
-			t.Error(testcase.Tag, ffmt, "!=", testcase.Fmt)
+		if tag.Fmt != testcase.Fmt {
+			t.Error(testcase.Tag, tag.Fmt, "!=", testcase.Fmt)
 		}
 	}
 }
 
+func TestParseTagBool(t *testing.T) {
+	testcases := []struct {
+		Tag    string
+		Expect *BoolFormat
+	}{
+		{"flag", nil},
+		{"flag,bool=Y|N", &BoolFormat{True: []string{"Y"}, False: []string{"N"}}},
+		{"flag,bool=x|", &BoolFormat{True: []string{"x"}, False: []string{""}}},
+		{"flag,bool=yes", nil},
+		{"flag,bool=a|b|c", nil},
+	}
+	for _, testcase := range testcases {
+		if tag := parseTag(testcase.Tag); !reflect.DeepEqual(tag.Bool, testcase.Expect) {
+			t.Error(testcase.Tag, tag.Bool, "!=", testcase.Expect)
+		}
+	}
+}
+
+func TestBoolFormat(t *testing.T) {
+	f := BoolFormat{
+		True:  []string{"yes", "y", "ja"},
+		False: []string{"no", "n", "nee", ""},
+	}
+
+	codec := &boolCodec{&f}
+
+	for s, expect := range map[string]bool{
+		"yes": true, "Y": true, "JA": true,
+		"no": false, "n": false, "Nee": false, "": false,
+	} {
+		var b bool
+		if err := codec.Decode(reflect.ValueOf(&b).Elem(), s); err != nil {
+			t.Error(s, err)
+		} else if b != expect {
+			t.Error(s, b, "!=", expect)
+		}
+	}
+
+	var b bool
+	if err := codec.Decode(reflect.ValueOf(&b).Elem(), "true"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Error("expected syntax error", err)
+	}
+
+	if s, _ := codec.Encode(reflect.ValueOf(true)); s != "yes" {
+		t.Error(s)
+	}
+
+	if s, _ := codec.Encode(reflect.ValueOf(false)); s != "no" {
+		t.Error(s)
+	}
+}
+
 func TestEmbeddedStruct(t *testing.T) {
 	type A struct{ A int }
 	type B struct{ B int }
@@ -253,3 +308,65 @@ func TestHeader(t *testing.T) {
 		t.Fatal(h)
 	}
 }
+
+// testMoney is stored in cents and rendered according to the column.
+type testMoney int64
+
+func (m *testMoney) MarshalCSVField(tag FieldTag) (string, error) {
+	if _, ok := tag.Lookup("cents"); ok {
+		return strconv.FormatInt(int64(*m), 10), nil
+	}
+	return fmt.Sprintf("%s %d.%02d", tag.Name, *m/100, *m%100), nil
+}
+
+func (m *testMoney) UnmarshalCSVField(tag FieldTag, s string) error {
+	if cur, _ := tag.Lookup("currency"); cur != "" {
+		s = strings.TrimPrefix(s, cur)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	*m = testMoney(n)
+	return err
+}
+
+// MarshalText is overridden by MarshalCSVField.
+func (m *testMoney) MarshalText() ([]byte, error) {
+	return nil, errors.New("should not be called")
+}
+
+func TestFieldMarshaler(t *testing.T) {
+	type struc struct {
+		Net   testMoney `csv:"net"`
+		Gross testMoney `csv:"gross,cents,currency=EUR"`
+	}
+
+	data := []struc{{1250, 1500}}
+
+	text, err := Marshal(&data)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(text) != "net,gross\nnet 12.50,1500\n" {
+		t.Fatal(string(text))
+	}
+
+	if err := Unmarshal([]byte("net,gross\n100,EUR200\n"), &data); err != nil {
+		t.Fatal(err)
+	} else if data[0].Net != 100 || data[0].Gross != 200 {
+		t.Fatal(data)
+	}
+}
+
+func TestFieldTagLookup(t *testing.T) {
+	tag := parseTag("name,flag,key=value,pattern=a,b")
+	if v, ok := tag.Lookup("flag"); !ok || v != "" {
+		t.Error("flag", v, ok)
+	}
+	if v, ok := tag.Lookup("key"); !ok || v != "value" {
+		t.Error("key", v, ok)
+	}
+	if v, ok := tag.Lookup("pattern"); !ok || v != "a,b" {
+		t.Error("pattern", v, ok)
+	}
+	if _, ok := tag.Lookup("ke"); ok {
+		t.Error("ke")
+	}
+}
@@ -0,0 +1,36 @@
+package csvbuddy
+
+import "reflect"
+
+// RowWriter encodes structs of type T one at a time.
+// It is a typed wrapper around Encoder.EncodeRow.
+type RowWriter[T any] struct {
+	e *Encoder
+}
+
+// NewRowWriter creates a RowWriter that encodes rows using e.
+// The Encoder must not be used for anything else until the RowWriter is closed.
+func NewRowWriter[T any](e *Encoder) *RowWriter[T] {
+	return &RowWriter[T]{e}
+}
+
+// Write encodes v. The header is written before the first row.
+func (w *RowWriter[T]) Write(v *T) error {
+	return w.e.EncodeRow(v)
+}
+
+// Flush writes any buffered rows to the output stream.
+func (w *RowWriter[T]) Flush() error {
+	return w.e.Flush()
+}
+
+// Close writes the header if no rows were written and flushes the buffered rows.
+func (w *RowWriter[T]) Close() error {
+	structType := innerTypeOf(reflect.TypeOf((*T)(nil)), reflect.Ptr, reflect.Struct)
+	if structType == nil {
+		return ErrInvalidArgument
+	} else if err := w.e.initRow(structType); err != nil {
+		return err
+	}
+	return w.e.Close()
+}
@@ -0,0 +1,39 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRowWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewRowWriter[testPeriod](NewEncoder(&b))
+
+	for i := 0; i < 3; i++ {
+		if err := w.Write(&testPeriod{i, i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "start,end\n0,1\n1,2\n2,3\n" {
+		t.Fatal(b.String())
+	}
+}
+
+func TestRowWriterEmpty(t *testing.T) {
+	var b bytes.Buffer
+	w := NewRowWriter[testPeriod](NewEncoder(&b))
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "start,end\n" {
+		t.Fatal(b.String())
+	}
+
+	var c bytes.Buffer
+	if err := NewRowWriter[int](NewEncoder(&c)).Close(); err != ErrInvalidArgument {
+		t.Fatal("expected ErrInvalidArgument", err)
+	}
+}
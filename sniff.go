@@ -0,0 +1,223 @@
+package csvbuddy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sniffSize is the maximum number of bytes inspected by Sniff.
+const sniffSize = 64 << 10
+
+// sniffDelimiters are the delimiters recognized by Sniff in order of preference.
+var sniffDelimiters = []rune{',', ';', '\t', '|'}
+
+// Sniff guesses the dialect of a CSV input stream and whether it has a header
+// by inspecting up to the first 64 KiB. It recognizes comma, semicolon, tab
+// and pipe delimiters, bare quotes, CRLF line endings and the byte order mark.
+// The prefix is peeked without being consumed if r is a *bufio.Reader
+// with a large enough buffer, and read from r otherwise.
+// The header detection is a heuristic that compares the first record
+// to the following records.
+func Sniff(r io.Reader) (dialect Dialect, hasHeader bool, err error) {
+	var prefix []byte
+	if br, ok := r.(*bufio.Reader); ok {
+		prefix, err = br.Peek(sniffSize)
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+	} else {
+		prefix = make([]byte, sniffSize)
+		var n int
+		n, err = io.ReadFull(r, prefix)
+		prefix = prefix[:n]
+	}
+
+	complete := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !complete {
+		return Dialect{}, false, err
+	} else if len(prefix) == 0 {
+		return Dialect{}, false, io.EOF
+	}
+
+	// only inspect complete lines unless the prefix is the whole input
+	if !complete {
+		if i := bytes.LastIndexByte(prefix, '\n'); i >= 0 {
+			prefix = prefix[:i+1]
+		}
+	}
+
+	dialect.Comma = ','
+	if bytes.HasPrefix(prefix, []byte(bom)) {
+		dialect.BOM = true
+		prefix = prefix[len(bom):]
+	}
+	dialect.UseCRLF = bytes.Contains(prefix, []byte("\r\n"))
+
+	var best [][]string
+	var bestScore int
+	for _, comma := range sniffDelimiters {
+		for _, lazy := range []bool{false, true} {
+			records := sniffRecords(prefix, comma, lazy)
+			if score := sniffScore(records); score > bestScore {
+				best, bestScore = records, score
+				dialect.Comma, dialect.LazyQuotes = comma, lazy
+			}
+			if len(records) > 0 && !lazy {
+				break // lazy quotes are only needed if strict parsing fails
+			}
+		}
+	}
+
+	// a single column
+	if bestScore == 0 {
+		best = sniffRecords(prefix, dialect.Comma, false)
+	}
+
+	dialect.TrimLeadingSpace = sniffLeadingSpace(best)
+	return dialect, sniffHeader(best), nil
+}
+
+// sniffRecords parses the records of prefix until the first error.
+func sniffRecords(prefix []byte, comma rune, lazy bool) (records [][]string) {
+	cr := csv.NewReader(bytes.NewReader(prefix))
+	cr.Comma = comma
+	cr.LazyQuotes = lazy
+	cr.FieldsPerRecord = -1
+	for {
+		record, err := cr.Read()
+		if err != nil {
+			if err != io.EOF {
+				return nil
+			}
+			return records
+		}
+		records = append(records, record)
+	}
+}
+
+// sniffScore rates how well records look like a table.
+// It is the number of records that have the most common number of fields
+// times the number of delimiters, and zero if there is only one field.
+func sniffScore(records [][]string) int {
+	counts := map[int]int{}
+	var mode int
+	for _, record := range records {
+		if counts[len(record)]++; counts[len(record)] > counts[mode] {
+			mode = len(record)
+		}
+	}
+	return counts[mode] * (mode - 1)
+}
+
+// sniffLeadingSpace reports whether all fields but the first begin with a space.
+func sniffLeadingSpace(records [][]string) bool {
+	var spaces int
+	for _, record := range records {
+		for _, field := range record[1:] {
+			if !strings.HasPrefix(field, " ") {
+				return false
+			}
+			spaces++
+		}
+	}
+	return spaces > 0
+}
+
+// sniffHeader guesses whether the first record is a header.
+// Every column in which the other records are all numeric or all of the same length
+// votes for a header if the first record differs from them and against it otherwise.
+// Without votes, including when there is only one record, the first record
+// is a header if its fields are distinct, non-empty and not numeric.
+func sniffHeader(records [][]string) bool {
+	if len(records) == 0 {
+		return false
+	}
+
+	isNumeric := func(s string) bool {
+		_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return err == nil
+	}
+
+	header, rows := records[0], records[1:]
+
+	var votes int
+	for j, name := range header {
+		if len(rows) == 0 {
+			break
+		}
+
+		numeric, length := true, -1
+		for _, row := range rows {
+			if j >= len(row) {
+				numeric, length = false, -2
+				break
+			}
+			numeric = numeric && isNumeric(row[j])
+			if length == -1 {
+				length = len(row[j])
+			} else if length != len(row[j]) {
+				length = -2
+			}
+		}
+
+		if numeric {
+			if isNumeric(name) {
+				votes--
+			} else {
+				votes++
+			}
+		} else if length >= 0 {
+			if len(name) == length {
+				votes--
+			} else {
+				votes++
+			}
+		}
+	}
+
+	if votes != 0 {
+		return votes > 0
+	}
+
+	names := map[string]struct{}{}
+	for _, name := range header {
+		if _, dup := names[name]; dup || name == "" || isNumeric(name) {
+			return false
+		}
+		names[name] = struct{}{}
+	}
+	return true
+}
+
+// AutoDetectDialect causes the Decoder to detect the dialect of the input stream
+// using Sniff before reading the header. If the input appears to have no header,
+// the input is decoded as if SkipHeader was called.
+// The detected dialect replaces the one set by SetDialect for each call to Decode,
+// Iterate or DecodeFileParallel, without changing the settings of the Decoder.
+// It cannot be combined with SetReaderFunc or SetFixedWidth, which cause decoding to fail.
+func (d *Decoder) AutoDetectDialect() { d.autoDetect = true }
+
+// detectDialect sniffs the dialect of r and returns a copy of the Decoder that uses it.
+func (d *Decoder) detectDialect(r io.Reader) (*Decoder, error) {
+	if d.readerFuncSet {
+		return nil, errors.New("csv: AutoDetectDialect cannot be combined with a custom ReaderFunc")
+	}
+
+	dialect, hasHeader, err := Sniff(r)
+	if err != nil {
+		return nil, err
+	}
+
+	detected := *d
+	detected.autoDetect = false
+	detected.SetDialect(dialect)
+	if !hasHeader {
+		detected.skipHeader = true
+	}
+	return &detected, nil
+}
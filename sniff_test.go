@@ -0,0 +1,89 @@
+package csvbuddy
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	for _, test := range []struct {
+		Text      string
+		Dialect   Dialect
+		HasHeader bool
+	}{
+		{"name,age\nStan,10\nIke,5\n", Dialect{Comma: ','}, true},
+		{"Stan;10\nIke;5\n", Dialect{Comma: ';'}, false},
+		{"a\tb\n1\t2\n3\t\"4\"\"\n", Dialect{Comma: '\t', LazyQuotes: true}, true},
+		{bom + "x|y\r\n\"1|2\"|3\r\n", Dialect{Comma: '|', UseCRLF: true, BOM: true}, true},
+		{"start, end\n1, 2\n", Dialect{Comma: ',', TrimLeadingSpace: true}, true},
+		{"name\nStan\nCartman\n", Dialect{Comma: ','}, true},
+		{"name,age\n", Dialect{Comma: ','}, true},
+	} {
+		dialect, hasHeader, err := Sniff(strings.NewReader(test.Text))
+		if err != nil {
+			t.Fatal(err)
+		} else if dialect != test.Dialect || hasHeader != test.HasHeader {
+			t.Fatalf("%q: %+v %v", test.Text, dialect, hasHeader)
+		}
+	}
+
+	if _, _, err := Sniff(strings.NewReader("")); err != io.EOF {
+		t.Fatal("expected io.EOF", err)
+	}
+}
+
+func TestSniffBufioReader(t *testing.T) {
+	text := "a;b\n" + strings.Repeat("1;2\n", sniffSize)
+	br := bufio.NewReaderSize(strings.NewReader(text), sniffSize)
+
+	if dialect, hasHeader, err := Sniff(br); err != nil || dialect.Comma != ';' || !hasHeader {
+		t.Fatal(dialect, hasHeader, err)
+	} else if line, _ := br.ReadString('\n'); line != "a;b\n" {
+		t.Fatal("prefix should not be consumed", line)
+	}
+}
+
+func TestDecoderAutoDetectDialect(t *testing.T) {
+	for _, text := range []string{
+		"start;end\n1;2\n3;4\n",
+		"1\t2\n3\t4\n",
+	} {
+		var data []testPeriod
+		d := NewDecoder(strings.NewReader(text))
+		d.AutoDetectDialect()
+		if err := d.Decode(&data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) {
+			t.Fatal("not equal", data)
+		}
+	}
+}
+
+func TestDecoderAutoDetectDialectSettings(t *testing.T) {
+	// the detected dialect does not carry over to the next call
+	d := NewDecoder(nil)
+	d.AutoDetectDialect()
+	for _, text := range []string{
+		"1\t2\n3\t4\n",
+		"start;end\n1;2\n3;4\n",
+	} {
+		var data []testPeriod
+		f := strings.NewReader(text)
+		if err := d.DecodeFileParallel(f, f.Size(), &data); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(data, []testPeriod{{1, 2}, {3, 4}}) {
+			t.Fatal("not equal", data)
+		}
+	}
+
+	var data []testPeriod
+	d = NewDecoder(strings.NewReader("start;end\n1;2\n"))
+	d.AutoDetectDialect()
+	d.SetReaderFunc(NewReader)
+	if err := d.Decode(&data); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,110 @@
+package csvbuddy
+
+import "reflect"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// source iterates over the structs of a slice, an iterator function or a channel.
+type source struct {
+	structType reflect.Type
+	each       func(fn func(row reflect.Value) error) error
+}
+
+// sourceOf returns the source of v, which must be a pointer to a slice of T,
+// a func(yield func(T) bool), a func(yield func(T, error) bool)
+// or a receive channel of T, where T is a struct or a pointer to a struct.
+func sourceOf(v reflect.Value) (*source, error) {
+	switch t := v.Type(); t.Kind() {
+	case reflect.Ptr:
+		if sliceStructType(t) == nil {
+			break
+		}
+		slice := v.Elem()
+		if slice.Type().Elem().Kind() == reflect.Struct {
+			return &source{slice.Type().Elem(), func(fn func(reflect.Value) error) error {
+				for i := 0; i < slice.Len(); i++ {
+					if err := fn(slice.Index(i)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}}, nil
+		}
+		rowOf, structType := rowFunc(slice.Type().Elem())
+		return &source{structType, func(fn func(reflect.Value) error) error {
+			for i := 0; i < slice.Len(); i++ {
+				if row, ok := rowOf(slice.Index(i)); !ok {
+					return ErrInvalidArgument
+				} else if err := fn(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}}, nil
+	case reflect.Func:
+		if t.NumIn() != 1 || t.NumOut() != 0 {
+			break
+		}
+		yieldType := t.In(0)
+		if yieldType.Kind() != reflect.Func || yieldType.NumOut() != 1 || yieldType.Out(0).Kind() != reflect.Bool {
+			break
+		} else if n := yieldType.NumIn(); n != 1 && (n != 2 || yieldType.In(1) != errorType) {
+			break
+		}
+		if rowOf, structType := rowFunc(yieldType.In(0)); rowOf != nil {
+			return &source{structType, func(fn func(reflect.Value) error) (err error) {
+				yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+					if len(args) == 2 && !args[1].IsNil() {
+						err = args[1].Interface().(error)
+					} else if row, ok := rowOf(args[0]); !ok {
+						err = ErrInvalidArgument
+					} else {
+						err = fn(row)
+					}
+					return []reflect.Value{reflect.ValueOf(err == nil)}
+				})
+				v.Call([]reflect.Value{yield})
+				return
+			}}, nil
+		}
+	case reflect.Chan:
+		if t.ChanDir()&reflect.RecvDir == 0 {
+			break
+		}
+		if rowOf, structType := rowFunc(t.Elem()); rowOf != nil {
+			return &source{structType, func(fn func(reflect.Value) error) error {
+				for {
+					x, ok := v.Recv()
+					if !ok {
+						return nil
+					} else if row, ok := rowOf(x); !ok {
+						return ErrInvalidArgument
+					} else if err := fn(row); err != nil {
+						return err
+					}
+				}
+			}}, nil
+		}
+	}
+	return nil, ErrInvalidArgument
+}
+
+// rowFunc returns a function that converts values of type t,
+// which must be a struct or a pointer to a struct, to addressable struct values.
+// It returns nil if t is neither.
+func rowFunc(t reflect.Type) (rowOf func(reflect.Value) (reflect.Value, bool), structType reflect.Type) {
+	switch {
+	case t.Kind() == reflect.Struct:
+		// structs are passed by value and must be copied to be addressable
+		scratch := reflect.New(t).Elem()
+		return func(x reflect.Value) (reflect.Value, bool) {
+			scratch.Set(x)
+			return scratch, true
+		}, t
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct:
+		return func(x reflect.Value) (reflect.Value, bool) {
+			return x.Elem(), !x.IsNil()
+		}, t.Elem()
+	}
+	return nil, nil
+}
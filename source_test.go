@@ -0,0 +1,82 @@
+package csvbuddy
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeSeq(t *testing.T) {
+	seq := func(yield func(testPeriod) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(testPeriod{i, i + 1}) {
+				return
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(seq); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "start,end\n0,1\n1,2\n2,3\n" {
+		t.Fatal(b.String())
+	}
+}
+
+func TestEncodeSeq2(t *testing.T) {
+	errSource := errors.New("source error")
+
+	var stopped bool
+	seq := func(yield func(*testPeriod, error) bool) {
+		if !yield(&testPeriod{0, 1}, nil) || !yield(nil, errSource) {
+			stopped = true
+			return
+		}
+		yield(&testPeriod{1, 2}, nil)
+	}
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode(seq); err != errSource {
+		t.Fatal("expected source error", err)
+	} else if !stopped {
+		t.Fatal("iterator should be stopped")
+	}
+
+	b.Reset()
+	nilrow := func(yield func(*testPeriod, error) bool) {
+		yield(nil, nil)
+	}
+	if err := NewEncoder(&b).Encode(nilrow); err != ErrInvalidArgument {
+		t.Fatal("expected ErrInvalidArgument", err)
+	}
+}
+
+func TestEncodeChan(t *testing.T) {
+	ch := make(chan *testPeriod, 3)
+	for i := 0; i < 3; i++ {
+		ch <- &testPeriod{i, i + 1}
+	}
+	close(ch)
+
+	var b bytes.Buffer
+	if err := NewEncoder(&b).Encode((<-chan *testPeriod)(ch)); err != nil {
+		t.Fatal(err)
+	} else if b.String() != "start,end\n0,1\n1,2\n2,3\n" {
+		t.Fatal(b.String())
+	}
+}
+
+func TestEncodeInvalidSource(t *testing.T) {
+	for _, v := range []interface{}{
+		func(yield func(int) bool) {},
+		func(yield func(testPeriod, int) bool) {},
+		func(yield func(testPeriod)) {},
+		make(chan<- testPeriod),
+		make(chan int),
+		&[]int{},
+	} {
+		if err := NewEncoder(&bytes.Buffer{}).Encode(v); err != ErrInvalidArgument {
+			t.Fatalf("%T: expected ErrInvalidArgument: %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package csvbuddy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ErrConstraint signals that a value violates a min, max, minlen, maxlen or pattern constraint.
+var ErrConstraint = errors.New("value violates constraint")
+
+// bound is a numeric lower or upper bound.
+type bound struct {
+	Text string // bound as written in the tag
+	I    int64
+	U    uint64
+	F    float64
+}
+
+// cmp compares v to b and returns -1, 0 or 1.
+func (b *bound) cmp(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if x := v.Int(); x < b.I {
+			return -1
+		} else if x > b.I {
+			return 1
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		if x := v.Uint(); x < b.U {
+			return -1
+		} else if x > b.U {
+			return 1
+		}
+	case reflect.Float32, reflect.Float64:
+		if x := v.Float(); x < b.F {
+			return -1
+		} else if x > b.F {
+			return 1
+		}
+	}
+	return 0
+}
+
+func parseBound(t reflect.Type, s string) (b *bound, err error) {
+	b = &bound{Text: s}
+	switch t.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		b.I, err = strconv.ParseInt(s, 10, 64)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		b.U, err = strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		b.F, err = strconv.ParseFloat(s, 64)
+	default:
+		err = errors.New("not a numeric type")
+	}
+	return
+}
+
+// constraints are the validation rules of a struct field.
+// Min and Max apply to the converted numeric value.
+// MinLen, MaxLen and Pattern apply to the field text.
+type constraints struct {
+	Min     *bound
+	Max     *bound
+	MinLen  int // -1 if unset
+	MaxLen  int // -1 if unset
+	Pattern *regexp.Regexp
+}
+
+func newConstraints(t reflect.Type, tag FieldTag) (c *constraints, err error) {
+	if tag.Min == "" && tag.Max == "" && tag.MinLen < 0 && tag.MaxLen < 0 && tag.Pattern == "" {
+		return nil, nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	c = &constraints{MinLen: tag.MinLen, MaxLen: tag.MaxLen}
+
+	if tag.Min != "" {
+		if c.Min, err = parseBound(t, tag.Min); err != nil {
+			return nil, fmt.Errorf("field '%s': invalid min: %w", tag.Name, err)
+		}
+	}
+
+	if tag.Max != "" {
+		if c.Max, err = parseBound(t, tag.Max); err != nil {
+			return nil, fmt.Errorf("field '%s': invalid max: %w", tag.Name, err)
+		}
+	}
+
+	if tag.Pattern != "" {
+		if c.Pattern, err = regexp.Compile(tag.Pattern); err != nil {
+			return nil, fmt.Errorf("field '%s': invalid pattern: %w", tag.Name, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *constraints) check(name string, v reflect.Value, s string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() { // optional field that was not set
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var violated string
+	if c.Min != nil && c.Min.cmp(v) < 0 {
+		violated = "min=" + c.Min.Text
+	} else if c.Max != nil && c.Max.cmp(v) > 0 {
+		violated = "max=" + c.Max.Text
+	} else if n := utf8.RuneCountInString(s); c.MinLen >= 0 && n < c.MinLen {
+		violated = "minlen=" + strconv.Itoa(c.MinLen)
+	} else if c.MaxLen >= 0 && n > c.MaxLen {
+		violated = "maxlen=" + strconv.Itoa(c.MaxLen)
+	} else if c.Pattern != nil && !c.Pattern.MatchString(s) {
+		violated = "pattern=" + c.Pattern.String()
+	} else {
+		return nil
+	}
+
+	return &FieldError{
+		Name:  name,
+		Value: s,
+		Err:   fmt.Errorf("%w %s", ErrConstraint, violated),
+	}
+}
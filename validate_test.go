@@ -0,0 +1,82 @@
+package csvbuddy
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTagConstraints(t *testing.T) {
+	tag := parseTag("code,min=1,max=9.5,minlen=2,maxlen=3,pattern=^[A-Z]{2,3}$")
+	if tag.Min != "1" || tag.Max != "9.5" || tag.MinLen != 2 || tag.MaxLen != 3 {
+		t.Error(tag)
+	} else if tag.Pattern != "^[A-Z]{2,3}$" {
+		t.Error(tag.Pattern)
+	}
+}
+
+func TestNewConstraintsInvalid(t *testing.T) {
+	for _, x := range []interface{}{
+		struct {
+			A int `csv:"a,min=x"`
+		}{},
+		struct {
+			A string `csv:"a,max=1"`
+		}{},
+		struct {
+			A string `csv:"a,pattern=("`
+		}{},
+	} {
+		if _, err := structFieldsOf(reflect.TypeOf(x)); err == nil {
+			t.Error("expected error", x)
+		}
+	}
+}
+
+func TestDecodeConstraints(t *testing.T) {
+	type struc struct {
+		Age   int      `csv:"age,min=0,max=150"`
+		Score *float64 `csv:"score,min=-1.5"`
+		Code  string   `csv:"code,minlen=2,maxlen=3,pattern=^[A-Z]+$"`
+		Count uint8    `csv:"count,max=10"`
+	}
+
+	var data []struc
+	if err := Unmarshal([]byte("age,score,code,count\n42,,NL,10\n0,-1.5,BEL,0"), &data); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 2 {
+		t.Fatal(data)
+	}
+
+	testcases := []struct {
+		Data      string
+		Name      string
+		Violation string
+		Column    int
+	}{
+		{"151,0,NL,1", "age", "max=150", 1},
+		{"-1,0,NL,1", "age", "min=0", 1},
+		{"1,-2,NL,1", "score", "min=-1.5", 3},
+		{"1,0,N,1", "code", "minlen=2", 5},
+		{"1,0,NLDE,1", "code", "maxlen=3", 5},
+		{"1,0,nl,1", "code", "pattern=^[A-Z]+$", 5},
+		{"1,0,NL,11", "count", "max=10", 8},
+	}
+
+	for _, testcase := range testcases {
+		testdata := "age,score,code,count\n" + testcase.Data
+		err := Unmarshal([]byte(testdata), &data)
+
+		var fe *FieldError
+		var pe *csv.ParseError
+		if !errors.As(err, &fe) || !errors.Is(err, ErrConstraint) {
+			t.Error(testcase.Data, "expected FieldError", err)
+		} else if fe.Name != testcase.Name || !strings.HasSuffix(fe.Err.Error(), testcase.Violation) {
+			t.Error(testcase.Data, err)
+		} else if !errors.As(err, &pe) || pe.Line != 2 || pe.Column != testcase.Column {
+			t.Error(testcase.Data, "wrong position", err)
+		}
+	}
+}